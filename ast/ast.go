@@ -1,6 +1,10 @@
 package ast
 
+import "github.com/vincentlabelle/monkey/token"
+
 type Node interface {
+	Pos() token.Position
+	End() token.Position
 	node()
 }
 
@@ -15,20 +19,30 @@ type Expression interface {
 }
 
 type Program struct {
+	Span
 	Statements []Statement
+	Comments   []*Comment
 }
 
 func (p *Program) node() {}
 
+type Comment struct {
+	Span
+	Text string
+}
+
 type LetStatement struct {
-	Name  *Identifier
-	Value Expression
+	Span
+	Name     *Identifier
+	Value    Expression
+	Exported bool
 }
 
 func (ls *LetStatement) node()          {}
 func (ls *LetStatement) statementNode() {}
 
 type ReturnStatement struct {
+	Span
 	Value Expression
 }
 
@@ -36,13 +50,48 @@ func (rs *ReturnStatement) node()          {}
 func (rs *ReturnStatement) statementNode() {}
 
 type ExpressionStatement struct {
+	Span
 	Expression Expression
 }
 
 func (es *ExpressionStatement) node()          {}
 func (es *ExpressionStatement) statementNode() {}
 
+type WhileStatement struct {
+	Span
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) node()          {}
+func (ws *WhileStatement) statementNode() {}
+
+type ForStatement struct {
+	Span
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) node()          {}
+func (fs *ForStatement) statementNode() {}
+
+type BreakStatement struct {
+	Span
+}
+
+func (bs *BreakStatement) node()          {}
+func (bs *BreakStatement) statementNode() {}
+
+type ContinueStatement struct {
+	Span
+}
+
+func (cs *ContinueStatement) node()          {}
+func (cs *ContinueStatement) statementNode() {}
+
 type BlockStatement struct {
+	Span
 	Statements []Statement
 }
 
@@ -50,6 +99,7 @@ func (bs *BlockStatement) node()          {}
 func (bs *BlockStatement) statementNode() {}
 
 type Identifier struct {
+	Span
 	Value string
 }
 
@@ -57,13 +107,23 @@ func (i *Identifier) node()           {}
 func (i *Identifier) expressionNode() {}
 
 type IntegerLiteral struct {
+	Span
 	Value int
 }
 
 func (il *IntegerLiteral) node()           {}
 func (il *IntegerLiteral) expressionNode() {}
 
+type FloatLiteral struct {
+	Span
+	Value float64
+}
+
+func (fl *FloatLiteral) node()           {}
+func (fl *FloatLiteral) expressionNode() {}
+
 type BooleanLiteral struct {
+	Span
 	Value bool
 }
 
@@ -71,6 +131,7 @@ func (bl *BooleanLiteral) node()           {}
 func (bl *BooleanLiteral) expressionNode() {}
 
 type FunctionLiteral struct {
+	Span
 	Name       string
 	Parameters []*Identifier
 	Body       *BlockStatement
@@ -79,7 +140,17 @@ type FunctionLiteral struct {
 func (fl *FunctionLiteral) node()           {}
 func (fl *FunctionLiteral) expressionNode() {}
 
+type MacroLiteral struct {
+	Span
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) node()           {}
+func (ml *MacroLiteral) expressionNode() {}
+
 type StringLiteral struct {
+	Span
 	Value string
 }
 
@@ -87,6 +158,7 @@ func (sl *StringLiteral) node()           {}
 func (sl *StringLiteral) expressionNode() {}
 
 type ArrayLiteral struct {
+	Span
 	Elements []Expression
 }
 
@@ -94,6 +166,7 @@ func (al *ArrayLiteral) node()           {}
 func (al *ArrayLiteral) expressionNode() {}
 
 type HashLiteral struct {
+	Span
 	Pairs map[HashKey]Expression
 }
 
@@ -101,6 +174,7 @@ func (hl *HashLiteral) node()           {}
 func (hl *HashLiteral) expressionNode() {}
 
 type PrefixExpression struct {
+	Span
 	Operator string
 	Right    Expression
 }
@@ -109,6 +183,7 @@ func (pe *PrefixExpression) node()           {}
 func (pe *PrefixExpression) expressionNode() {}
 
 type InfixExpression struct {
+	Span
 	Left     Expression
 	Operator string
 	Right    Expression
@@ -117,7 +192,17 @@ type InfixExpression struct {
 func (ie *InfixExpression) node()           {}
 func (ie *InfixExpression) expressionNode() {}
 
+type AssignExpression struct {
+	Span
+	Target Expression
+	Value  Expression
+}
+
+func (ae *AssignExpression) node()           {}
+func (ae *AssignExpression) expressionNode() {}
+
 type IfExpression struct {
+	Span
 	Condition   Expression
 	Consequence *BlockStatement
 	Alternative *BlockStatement
@@ -127,6 +212,7 @@ func (ie *IfExpression) node()           {}
 func (ie *IfExpression) expressionNode() {}
 
 type CallExpression struct {
+	Span
 	Function  Expression
 	Arguments []Expression
 }
@@ -134,7 +220,16 @@ type CallExpression struct {
 func (ce *CallExpression) node()           {}
 func (ce *CallExpression) expressionNode() {}
 
+type ImportExpression struct {
+	Span
+	Path string
+}
+
+func (ie *ImportExpression) node()           {}
+func (ie *ImportExpression) expressionNode() {}
+
 type IndexExpression struct {
+	Span
 	Left  Expression
 	Index Expression
 }
@@ -0,0 +1,67 @@
+package ast
+
+import (
+	"slices"
+
+	"github.com/vincentlabelle/monkey/token"
+)
+
+const (
+	LOWEST = iota
+	ASSIGN
+	OR
+	AND
+	EQUALS
+	LESSGREATER
+	BITOR
+	BITXOR
+	BITAND
+	SHIFT
+	SUM
+	PRODUCT
+	PREFIX
+	POWER
+	CALL
+	INDEX
+)
+
+var precedences = map[token.TokenType]int{
+	token.ASSIGN:    ASSIGN,
+	token.OR:        OR,
+	token.AND:       AND,
+	token.EQ:        EQUALS,
+	token.NE:        EQUALS,
+	token.LT:        LESSGREATER,
+	token.GT:        LESSGREATER,
+	token.LE:        LESSGREATER,
+	token.GE:        LESSGREATER,
+	token.PIPE:      BITOR,
+	token.CARET:     BITXOR,
+	token.AMPERSAND: BITAND,
+	token.SHL:       SHIFT,
+	token.SHR:       SHIFT,
+	token.PLUS:      SUM,
+	token.MINUS:     SUM,
+	token.SLASH:     PRODUCT,
+	token.ASTERISK:  PRODUCT,
+	token.PERCENT:   PRODUCT,
+	token.POWER:     POWER,
+	token.LPAREN:    CALL,
+	token.LBRACKET:  INDEX,
+	token.DOT:       INDEX,
+}
+
+var rightAssociative = []token.TokenType{
+	token.POWER,
+}
+
+func Precedence(type_ token.TokenType) int {
+	if precedence, ok := precedences[type_]; ok {
+		return precedence
+	}
+	return LOWEST
+}
+
+func IsRightAssociative(type_ token.TokenType) bool {
+	return slices.Contains(rightAssociative, type_)
+}
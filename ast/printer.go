@@ -0,0 +1,444 @@
+package ast
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vincentlabelle/monkey/token"
+)
+
+const indentation = "    "
+
+const highest = INDEX + 1
+
+type printer struct {
+	builder  strings.Builder
+	indent   int
+	line     int
+	opened   bool
+	comments []*Comment
+}
+
+func Format(node Node) string {
+	p := &printer{}
+	switch n := node.(type) {
+	case *Program:
+		p.comments = n.Comments
+		p.printProgram(n)
+	case Statement:
+		p.printStatement(n)
+	case Expression:
+		p.printExpression(n, LOWEST)
+	}
+	return p.builder.String()
+}
+
+func (p *printer) write(s string) {
+	p.builder.WriteString(s)
+}
+
+func (p *printer) newline(pos token.Position) {
+	if p.builder.Len() > 0 {
+		if !p.opened && pos.IsValid() && pos.Line > p.line+1 {
+			p.write("\n")
+		}
+		p.write("\n")
+	}
+	p.write(strings.Repeat(indentation, p.indent))
+	p.opened = false
+}
+
+func (p *printer) advance(pos token.Position) {
+	if pos.IsValid() && pos.Line > p.line {
+		p.line = pos.Line
+	}
+}
+
+func (p *printer) printProgram(program *Program) {
+	p.printStatements(program.Statements)
+	for len(p.comments) > 0 {
+		p.printComment()
+	}
+	if p.builder.Len() > 0 {
+		p.write("\n")
+	}
+}
+
+func (p *printer) printStatements(statements []Statement) {
+	for i, statement := range statements {
+		p.printComments(statement.Pos())
+		p.newline(statement.Pos())
+		if isSelfTerminating(statement, statements[i+1:]) {
+			expression := statement.(*ExpressionStatement).Expression
+			p.printExpression(expression, LOWEST)
+		} else {
+			p.printStatement(statement)
+		}
+		p.advance(statement.End())
+		p.printTrailingComment(statement.End())
+	}
+}
+
+func isSelfTerminating(statement Statement, rest []Statement) bool {
+	s, ok := statement.(*ExpressionStatement)
+	if !ok {
+		return false
+	}
+	if _, ok := s.Expression.(*IfExpression); !ok {
+		return false
+	}
+	if len(rest) == 0 {
+		return true
+	}
+	next := Format(rest[0])
+	return next == "" || !strings.ContainsAny(next[:1], "-([")
+}
+
+func (p *printer) printComments(before token.Position) {
+	for p.hasCommentBefore(before) {
+		p.printComment()
+	}
+}
+
+func (p *printer) hasCommentBefore(pos token.Position) bool {
+	if len(p.comments) == 0 || !pos.IsValid() {
+		return false
+	}
+	return p.comments[0].Pos().Offset < pos.Offset
+}
+
+func (p *printer) printComment() {
+	comment := p.comments[0]
+	p.comments = p.comments[1:]
+	p.newline(comment.Pos())
+	p.write(comment.Text)
+	p.advance(comment.End())
+	p.printTrailingComment(comment.End())
+}
+
+func (p *printer) printTrailingComment(end token.Position) {
+	for p.hasCommentOn(end) {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+		p.write(" " + comment.Text)
+		p.advance(comment.End())
+		end = comment.End()
+	}
+}
+
+func (p *printer) hasCommentOn(end token.Position) bool {
+	if len(p.comments) == 0 || !end.IsValid() {
+		return false
+	}
+	return p.comments[0].Pos().Line == end.Line
+}
+
+func (p *printer) printInlineComments(before token.Position) {
+	for p.hasCommentBefore(before) {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+		p.write(comment.Text)
+		if isLineComment(comment) {
+			p.write("\n" + strings.Repeat(indentation, p.indent+1))
+		} else {
+			p.write(" ")
+		}
+		p.advance(comment.End())
+	}
+}
+
+func (p *printer) printClosingComments(end token.Position) {
+	for p.hasCommentBefore(end) {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+		p.write(" " + comment.Text)
+		if isLineComment(comment) {
+			p.write("\n" + strings.Repeat(indentation, p.indent))
+		}
+		p.advance(comment.End())
+	}
+}
+
+func isLineComment(comment *Comment) bool {
+	return strings.HasPrefix(comment.Text, "//")
+}
+
+func (p *printer) printStatement(statement Statement) {
+	switch s := statement.(type) {
+	case *LetStatement:
+		p.printLetStatement(s)
+	case *ReturnStatement:
+		p.printReturnStatement(s)
+	case *ExpressionStatement:
+		p.printExpression(s.Expression, LOWEST)
+		p.write(";")
+	case *WhileStatement:
+		p.write("while (")
+		p.printExpression(s.Condition, LOWEST)
+		p.write(") ")
+		p.printBlock(s.Body)
+	case *ForStatement:
+		p.write("for (" + s.Variable.Value + " in ")
+		p.printExpression(s.Iterable, LOWEST)
+		p.write(") ")
+		p.printBlock(s.Body)
+	case *BreakStatement:
+		p.write("break;")
+	case *ContinueStatement:
+		p.write("continue;")
+	case *BlockStatement:
+		p.printBlock(s)
+	}
+}
+
+func (p *printer) printLetStatement(statement *LetStatement) {
+	if statement.Exported {
+		p.write("export ")
+	}
+	p.write("let " + statement.Name.Value + " = ")
+	p.printExpression(statement.Value, LOWEST)
+	p.write(";")
+}
+
+func (p *printer) printReturnStatement(statement *ReturnStatement) {
+	p.write("return")
+	if statement.Value != nil {
+		p.write(" ")
+		p.printExpression(statement.Value, LOWEST)
+	}
+	p.write(";")
+}
+
+func (p *printer) printBlock(block *BlockStatement) {
+	if len(block.Statements) == 0 && !p.hasCommentBefore(block.End()) {
+		p.write("{}")
+		return
+	}
+	p.write("{")
+	p.indent++
+	p.opened = true
+	p.printStatements(block.Statements)
+	p.printComments(block.End())
+	p.indent--
+	p.opened = false
+	p.newline(token.Position{})
+	p.write("}")
+}
+
+func (p *printer) printExpression(expression Expression, precedence int) {
+	p.printInlineComments(expression.Pos())
+	if getPrecedence(expression) < precedence {
+		p.write("(")
+		defer p.write(")")
+	}
+	switch e := expression.(type) {
+	case *Identifier:
+		p.write(e.Value)
+	case *IntegerLiteral:
+		p.write(strconv.Itoa(e.Value))
+	case *FloatLiteral:
+		p.write(formatFloat(e.Value))
+	case *BooleanLiteral:
+		p.write(strconv.FormatBool(e.Value))
+	case *StringLiteral:
+		p.write(quote(e.Value))
+	case *ImportExpression:
+		p.write("import " + quote(e.Path))
+	case *ArrayLiteral:
+		p.write("[")
+		p.printExpressions(e.Elements)
+		p.printClosingComments(e.End())
+		p.write("]")
+	case *HashLiteral:
+		p.printHashLiteral(e)
+	case *PrefixExpression:
+		p.write(e.Operator)
+		p.printExpression(e.Right, PREFIX)
+	case *InfixExpression:
+		p.printInfixExpression(e)
+	case *AssignExpression:
+		p.printExpression(e.Target, ASSIGN+1)
+		p.write(" = ")
+		p.printExpression(e.Value, ASSIGN)
+	case *IfExpression:
+		p.printIfExpression(e)
+	case *FunctionLiteral:
+		p.printLiteral("fn", e.Parameters, e.Body)
+	case *MacroLiteral:
+		p.printLiteral("macro", e.Parameters, e.Body)
+	case *CallExpression:
+		p.printExpression(e.Function, CALL)
+		p.write("(")
+		p.printExpressions(e.Arguments)
+		p.printClosingComments(e.End())
+		p.write(")")
+	case *IndexExpression:
+		p.printIndexExpression(e)
+	}
+}
+
+func getPrecedence(expression Expression) int {
+	switch e := expression.(type) {
+	case *IntegerLiteral:
+		if e.Value < 0 {
+			return PREFIX
+		}
+	case *FloatLiteral:
+		if math.Signbit(e.Value) {
+			return PREFIX
+		}
+	case *PrefixExpression:
+		return PREFIX
+	case *InfixExpression:
+		return Precedence(getOperatorType(e.Operator))
+	case *AssignExpression:
+		return ASSIGN
+	case *CallExpression:
+		return CALL
+	case *IndexExpression:
+		return INDEX
+	}
+	return highest
+}
+
+func getOperatorType(operator string) token.TokenType {
+	if type_, ok := token.Binary[operator]; ok {
+		return type_
+	}
+	return token.Unary[operator[0]]
+}
+
+func formatFloat(value float64) string {
+	s := strconv.FormatFloat(value, 'g', -1, 64)
+	if strings.ContainsAny(s, ".eIN") {
+		return s
+	}
+	return s + ".0"
+}
+
+func quote(value string) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		builder.WriteString(escape(value[i]))
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+func escape(char byte) string {
+	switch char {
+	case '"':
+		return `\"`
+	case '\\':
+		return `\\`
+	case '\n':
+		return `\n`
+	case '\t':
+		return `\t`
+	}
+	if char < 0x20 || char == 0x7f {
+		return fmt.Sprintf(`\u{%x}`, char)
+	}
+	return string([]byte{char})
+}
+
+func (p *printer) printExpressions(expressions []Expression) {
+	for i, expression := range expressions {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.printExpression(expression, LOWEST)
+	}
+}
+
+func (p *printer) printHashLiteral(hash *HashLiteral) {
+	p.write("{")
+	for i, key := range SortHashKeys(maps.Keys(hash.Pairs)) {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.printExpression(key.Expression, LOWEST)
+		p.write(": ")
+		p.printExpression(hash.Pairs[key], LOWEST)
+	}
+	p.printClosingComments(hash.End())
+	p.write("}")
+}
+
+func (p *printer) printInfixExpression(expression *InfixExpression) {
+	type_ := getOperatorType(expression.Operator)
+	left, right := Precedence(type_), Precedence(type_)+1
+	if IsRightAssociative(type_) {
+		left, right = right, left
+	}
+	if getPrecedence(expression.Right) == PREFIX {
+		right = PREFIX
+	}
+	p.printExpression(expression.Left, left)
+	p.write(" " + expression.Operator + " ")
+	p.printExpression(expression.Right, right)
+}
+
+func (p *printer) printIfExpression(expression *IfExpression) {
+	p.write("if (")
+	p.printExpression(expression.Condition, LOWEST)
+	p.write(") ")
+	p.printBlock(expression.Consequence)
+	if expression.Alternative != nil {
+		p.write(" else ")
+		p.printBlock(expression.Alternative)
+	}
+}
+
+func (p *printer) printLiteral(
+	keyword string,
+	parameters []*Identifier,
+	body *BlockStatement,
+) {
+	names := make([]string, len(parameters))
+	for i, parameter := range parameters {
+		names[i] = parameter.Value
+	}
+	p.write(keyword + "(" + strings.Join(names, ", ") + ") ")
+	p.printBlock(body)
+}
+
+func (p *printer) printIndexExpression(expression *IndexExpression) {
+	p.printExpression(expression.Left, INDEX)
+	if name, ok := getMemberName(expression.Index); ok {
+		p.write("." + name)
+		return
+	}
+	p.write("[")
+	p.printExpression(expression.Index, LOWEST)
+	p.write("]")
+}
+
+func getMemberName(index Expression) (string, bool) {
+	literal, ok := index.(*StringLiteral)
+	if !ok || !isIdentifier(literal.Value) {
+		return "", false
+	}
+	return literal.Value, true
+}
+
+func isIdentifier(value string) bool {
+	if value == "" || !utf8.ValidString(value) {
+		return false
+	}
+	if _, ok := token.Keywords[value]; ok {
+		return false
+	}
+	for _, char := range value {
+		if !unicode.IsLetter(char) && char != '_' {
+			return false
+		}
+	}
+	return true
+}
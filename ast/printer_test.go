@@ -0,0 +1,270 @@
+package ast_test
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/lexer"
+	"github.com/vincentlabelle/monkey/parser"
+)
+
+func TestFormat(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"let   x=1;x", "let x = 1;\nx;\n"},
+		{"export let x = 1", "export let x = 1;\n"},
+		{"1 + 2 * 3; (1 + 2) * 3;", "1 + 2 * 3;\n(1 + 2) * 3;\n"},
+		{"1 - (2 - 3); (1 - 2) - 3;", "1 - (2 - 3);\n1 - 2 - 3;\n"},
+		{"2 ** (3 ** 2); (2 ** 3) ** 2;", "2 ** 3 ** 2;\n(2 ** 3) ** 2;\n"},
+		{"(-2) ** 2; -(2 ** 2); - -x;", "(-2) ** 2;\n-2 ** 2;\n--x;\n"},
+		{"2 ** (-x); (2 ** -x) * 3;", "2 ** -x;\n2 ** -x * 3;\n"},
+		{"a = (b = 1); (a || b) && c;", "a = b = 1;\n(a || b) && c;\n"},
+		{"(f)(1)(2); (a[0])[1]; (fn() {})();", "f(1)(2);\na[0][1];\nfn() {}();\n"},
+		{`a["b"]; a["if"]; a["b c"]; a[1];`, "a.b;\na[\"if\"];\na[\"b c\"];\na[1];\n"},
+		{"2.5; 2e3; 1e21; 0.1;", "2.5;\n2000.0;\n1e+21;\n0.1;\n"},
+		{"\"a\\\"b\\\\c\\nd\\te\"; `raw\\`;", "\"a\\\"b\\\\c\\nd\\te\";\n\"raw\\\\\";\n"},
+		{`{"b": 1, "a": [1,2]}; {};`, "{\"b\": 1, \"a\": [1, 2]};\n{};\n"},
+		{`let m = import "m.mk"; m.x;`, "let m = import \"m.mk\";\nm.x;\n"},
+		{
+			"if (x) { 1 }; -1; if (x) { 1 }; (a + b) * c; if (x) { 1 }; [1];",
+			"if (x) {\n    1;\n};\n-1;\nif (x) {\n    1;\n};\n(a + b) * c;\n" +
+				"if (x) {\n    1;\n};\n[1];\n",
+		},
+		{
+			"if (x) { 1 } else { 2 }; if (x) {}",
+			"if (x) {\n    1;\n} else {\n    2;\n}\nif (x) {}\n",
+		},
+		{
+			"let f = fn(a, b) { return a + b; };",
+			"let f = fn(a, b) {\n    return a + b;\n};\n",
+		},
+		{
+			"let m = macro(x) { quote(unquote(x)) };",
+			"let m = macro(x) {\n    quote(unquote(x));\n};\n",
+		},
+		{
+			"while (true) { for (x in xs) { if (x) { break } continue } }",
+			"while (true) {\n" +
+				"    for (x in xs) {\n" +
+				"        if (x) {\n" +
+				"            break;\n" +
+				"        }\n" +
+				"        continue;\n" +
+				"    }\n" +
+				"}\n",
+		},
+		{
+			"let a = 1;\n\n\n\nlet b = 2;\nlet c = fn() {\n\n    a;\n\n    b;\n\n};",
+			"let a = 1;\n\nlet b = 2;\nlet c = fn() {\n    a;\n\n    b;\n};\n",
+		},
+	}
+
+	for _, s := range setup {
+		actual := ast.Format(parse(t, s.input))
+		if actual != s.expected {
+			t.Fatalf(
+				"format mismatch. got=%q, expected=%q",
+				actual,
+				s.expected,
+			)
+		}
+	}
+}
+
+func TestFormatComments(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected string
+	}{
+		{"// only", "// only\n"},
+		{
+			"// head\nlet x = 1; // tail\n\n/* block */\nx;",
+			"// head\nlet x = 1; // tail\n\n/* block */\nx;\n",
+		},
+		{
+			"let f = fn() {\n// inside\n1;\n// last\n};\nif (x) { /* empty */ }",
+			"let f = fn() {\n" +
+				"    // inside\n" +
+				"    1;\n" +
+				"    // last\n" +
+				"};\n" +
+				"if (x) {\n" +
+				"    /* empty */\n" +
+				"}\n",
+		},
+		{
+			"let a = [1, // one\n2];\nlet b = 3;",
+			"let a = [1, // one\n    2];\nlet b = 3;\n",
+		},
+		{
+			"let h = {\"a\": 1, // one\n\"b\": /* two */ 2 // end\n};",
+			"let h = {\"a\": 1, // one\n    \"b\": /* two */ 2 // end\n};\n",
+		},
+		{
+			"f(/* a */ 1, // b\n2 /* c */);\nlet x = 1 + /* d */ 2;",
+			"f(/* a */ 1, // b\n    2 /* c */);\nlet x = 1 + /* d */ 2;\n",
+		},
+		{
+			"/* a */ /* b */\nx; /* c */ /* d */",
+			"/* a */ /* b */\nx; /* c */ /* d */\n",
+		},
+	}
+
+	for _, s := range setup {
+		actual := ast.Format(parse(t, s.input))
+		if actual != s.expected {
+			t.Fatalf(
+				"format mismatch. got=%q, expected=%q",
+				actual,
+				s.expected,
+			)
+		}
+	}
+}
+
+func TestFormatNode(t *testing.T) {
+	program := parse(t, "let x = 1 + 2 * (3 - 4);")
+	let := program.Statements[0].(*ast.LetStatement)
+	setup := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{let, "let x = 1 + 2 * (3 - 4);"},
+		{let.Value, "1 + 2 * (3 - 4)"},
+		{
+			&ast.InfixExpression{
+				Left:     &ast.IntegerLiteral{Value: -1},
+				Operator: "**",
+				Right:    &ast.FloatLiteral{Value: -0.5},
+			},
+			"(-1) ** -0.5",
+		},
+	}
+
+	for _, s := range setup {
+		actual := ast.Format(s.node)
+		if actual != s.expected {
+			t.Fatalf(
+				"format mismatch. got=%q, expected=%q",
+				actual,
+				s.expected,
+			)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	inputs := []string{
+		`let x = 5 * (2 + -3) / 4 % 3 ** 2 ** -1; x;`,
+		`!(true == false) != (1 < 2) && (3 >= 4 || 5 <= 6);`,
+		`(1 | 2) ^ 3 & ~4 << 1 >> (2 + 1); (a = b) + 1;`,
+		`let f = fn(a, b) { if (a) { return b; } else { a = b; } };`,
+		`let add = fn(x) { fn(y) { x + y } }; add(1)(2); [1, 2][0];`,
+		`let h = {"z": [fn() {}], 1: {true: 2.5e-3}, "a": "\u{1F600}\u{1}"};`,
+		`h.z[0](); h["a b"]; (if (x) { 1 } else { 2 })["a"];`,
+		"while (i < 10) { i = i + 1; if (i % 2 == 0) { continue; } break; }",
+		"if (a) { 1 }; -1; if (b) { 2 }; (c); if (d) { 3 }; [4]; if (e) {}",
+		`for (x in [1, 2]) { puts(x); } let m = macro(a) { quote(unquote(a)); };`,
+		`export let lib = import "./lib.mk"; lib.f(lib.x);`,
+		"// comment\nlet a = 1; /* b */ let c = `raw\n\\`; // d\n",
+		"let h = {\"a\": [1, // b\n2], /* c */ \"d\": f(/* e */ 3 // f\n)};",
+	}
+
+	for _, input := range inputs {
+		original := parse(t, input)
+		formatted := ast.Format(original)
+		reparsed := parse(t, formatted)
+		if dump(reparsed) != dump(original) {
+			t.Fatalf(
+				"round trip mismatch for %q. got=%v, expected=%v",
+				formatted,
+				dump(reparsed),
+				dump(original),
+			)
+		}
+		if again := ast.Format(reparsed); again != formatted {
+			t.Fatalf(
+				"format not idempotent. got=%q, expected=%q",
+				again,
+				formatted,
+			)
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	lex := lexer.New(input)
+	lex.KeepComments()
+	p := parser.New(lex)
+	program, errors := p.ParseProgram()
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors for %q. got=%v", input, errors)
+	}
+	return program
+}
+
+func dump(node any) string {
+	var builder strings.Builder
+	dumpValue(&builder, reflect.ValueOf(node))
+	return builder.String()
+}
+
+func dumpValue(builder *strings.Builder, value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			builder.WriteString("nil")
+			return
+		}
+		dumpValue(builder, value.Elem())
+	case reflect.Struct:
+		dumpStruct(builder, value)
+	case reflect.Slice:
+		builder.WriteString("[")
+		for i := 0; i < value.Len(); i++ {
+			dumpValue(builder, value.Index(i))
+			builder.WriteString(" ")
+		}
+		builder.WriteString("]")
+	case reflect.Map:
+		dumpPairs(builder, value.Interface().(map[ast.HashKey]ast.Expression))
+	default:
+		fmt.Fprintf(builder, "%#v", value.Interface())
+	}
+}
+
+func dumpStruct(builder *strings.Builder, value reflect.Value) {
+	builder.WriteString(value.Type().Name() + "{")
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Type == reflect.TypeOf(ast.Span{}) {
+			continue
+		}
+		builder.WriteString(field.Name + ":")
+		dumpValue(builder, value.Field(i))
+		builder.WriteString(" ")
+	}
+	builder.WriteString("}")
+}
+
+func dumpPairs(
+	builder *strings.Builder,
+	pairs map[ast.HashKey]ast.Expression,
+) {
+	builder.WriteString("{")
+	for _, key := range ast.SortHashKeys(maps.Keys(pairs)) {
+		fmt.Fprintf(builder, "%v:", key.Index)
+		dumpValue(builder, reflect.ValueOf(key.Expression))
+		builder.WriteString("=")
+		dumpValue(builder, reflect.ValueOf(pairs[key]))
+		builder.WriteString(" ")
+	}
+	builder.WriteString("}")
+}
@@ -0,0 +1,174 @@
+package ast
+
+import "maps"
+
+func Rewrite(node Node, fn func(Node) Node) Node {
+	switch n := node.(type) {
+	case *Program:
+		c := *n
+		c.Statements = rewriteStatements(n.Statements, fn)
+		node = &c
+	case *LetStatement:
+		c := *n
+		c.Name = rewriteIdentifier(n.Name, fn)
+		c.Value = rewriteExpression(n.Value, fn)
+		node = &c
+	case *ReturnStatement:
+		c := *n
+		c.Value = rewriteExpression(n.Value, fn)
+		node = &c
+	case *ExpressionStatement:
+		c := *n
+		c.Expression = rewriteExpression(n.Expression, fn)
+		node = &c
+	case *WhileStatement:
+		c := *n
+		c.Condition = rewriteExpression(n.Condition, fn)
+		c.Body = rewriteBlock(n.Body, fn)
+		node = &c
+	case *ForStatement:
+		c := *n
+		c.Variable = rewriteIdentifier(n.Variable, fn)
+		c.Iterable = rewriteExpression(n.Iterable, fn)
+		c.Body = rewriteBlock(n.Body, fn)
+		node = &c
+	case *BlockStatement:
+		c := *n
+		c.Statements = rewriteStatements(n.Statements, fn)
+		node = &c
+	case *FunctionLiteral:
+		c := *n
+		c.Parameters = rewriteIdentifiers(n.Parameters, fn)
+		c.Body = rewriteBlock(n.Body, fn)
+		node = &c
+	case *MacroLiteral:
+		c := *n
+		c.Parameters = rewriteIdentifiers(n.Parameters, fn)
+		c.Body = rewriteBlock(n.Body, fn)
+		node = &c
+	case *ArrayLiteral:
+		c := *n
+		c.Elements = rewriteExpressions(n.Elements, fn)
+		node = &c
+	case *HashLiteral:
+		c := *n
+		c.Pairs = rewritePairs(n.Pairs, fn)
+		node = &c
+	case *PrefixExpression:
+		c := *n
+		c.Right = rewriteExpression(n.Right, fn)
+		node = &c
+	case *InfixExpression:
+		c := *n
+		c.Left = rewriteExpression(n.Left, fn)
+		c.Right = rewriteExpression(n.Right, fn)
+		node = &c
+	case *AssignExpression:
+		c := *n
+		c.Target = rewriteExpression(n.Target, fn)
+		c.Value = rewriteExpression(n.Value, fn)
+		node = &c
+	case *IfExpression:
+		c := *n
+		c.Condition = rewriteExpression(n.Condition, fn)
+		c.Consequence = rewriteBlock(n.Consequence, fn)
+		c.Alternative = rewriteBlock(n.Alternative, fn)
+		node = &c
+	case *CallExpression:
+		c := *n
+		c.Function = rewriteExpression(n.Function, fn)
+		c.Arguments = rewriteExpressions(n.Arguments, fn)
+		node = &c
+	case *IndexExpression:
+		c := *n
+		c.Left = rewriteExpression(n.Left, fn)
+		c.Index = rewriteExpression(n.Index, fn)
+		node = &c
+	}
+	return fn(node)
+}
+
+func rewriteStatements(
+	statements []Statement,
+	fn func(Node) Node,
+) []Statement {
+	rewritten := make([]Statement, len(statements))
+	for i, statement := range statements {
+		rewritten[i] = statement
+		if r, ok := Rewrite(statement, fn).(Statement); ok {
+			rewritten[i] = r
+		}
+	}
+	return rewritten
+}
+
+func rewriteBlock(
+	statement *BlockStatement,
+	fn func(Node) Node,
+) *BlockStatement {
+	if statement == nil {
+		return nil
+	}
+	if rewritten, ok := Rewrite(statement, fn).(*BlockStatement); ok {
+		return rewritten
+	}
+	return statement
+}
+
+func rewriteIdentifiers(
+	identifiers []*Identifier,
+	fn func(Node) Node,
+) []*Identifier {
+	rewritten := make([]*Identifier, len(identifiers))
+	for i, identifier := range identifiers {
+		rewritten[i] = rewriteIdentifier(identifier, fn)
+	}
+	return rewritten
+}
+
+func rewriteIdentifier(
+	identifier *Identifier,
+	fn func(Node) Node,
+) *Identifier {
+	if identifier == nil {
+		return nil
+	}
+	if rewritten, ok := Rewrite(identifier, fn).(*Identifier); ok {
+		return rewritten
+	}
+	return identifier
+}
+
+func rewriteExpressions(
+	expressions []Expression,
+	fn func(Node) Node,
+) []Expression {
+	rewritten := make([]Expression, len(expressions))
+	for i, expression := range expressions {
+		rewritten[i] = rewriteExpression(expression, fn)
+	}
+	return rewritten
+}
+
+func rewriteExpression(expression Expression, fn func(Node) Node) Expression {
+	if expression == nil {
+		return nil
+	}
+	if rewritten, ok := Rewrite(expression, fn).(Expression); ok {
+		return rewritten
+	}
+	return expression
+}
+
+func rewritePairs(
+	pairs map[HashKey]Expression,
+	fn func(Node) Node,
+) map[HashKey]Expression {
+	rewritten := map[HashKey]Expression{}
+	for _, key := range SortHashKeys(maps.Keys(pairs)) {
+		value := pairs[key]
+		key.Expression = rewriteExpression(key.Expression, fn)
+		rewritten[key] = rewriteExpression(value, fn)
+	}
+	return rewritten
+}
@@ -0,0 +1,93 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/vincentlabelle/monkey/ast"
+)
+
+func TestRewrite(t *testing.T) {
+	setup := []struct {
+		input    string
+		fn       func(ast.Node) ast.Node
+		expected string
+	}{
+		{
+			"let x = {1: [2], 3: -4}; x[5] = 6;",
+			func(node ast.Node) ast.Node {
+				if integer, ok := node.(*ast.IntegerLiteral); ok {
+					return &ast.IntegerLiteral{Value: integer.Value * 10}
+				}
+				return node
+			},
+			"let x = {10: [20], 30: -40};\nx[50] = 60;\n",
+		},
+		{
+			"let f = fn(a, b) { a + b }; for (a in f) { while (a) { a } }",
+			func(node ast.Node) ast.Node {
+				if identifier, ok := node.(*ast.Identifier); ok {
+					return &ast.Identifier{Value: identifier.Value + "_"}
+				}
+				return node
+			},
+			"let f_ = fn(a_, b_) {\n    a_ + b_;\n};\n" +
+				"for (a_ in f_) {\n    while (a_) {\n        a_;\n    }\n}\n",
+		},
+		{
+			"if (1 + 2) { f(3 + 4) } else { return 5 + 6; }",
+			func(node ast.Node) ast.Node {
+				if _, ok := node.(*ast.InfixExpression); ok {
+					return &ast.StringLiteral{Value: "sum"}
+				}
+				return node
+			},
+			"if (\"sum\") {\n    f(\"sum\");\n} else {\n    return \"sum\";\n}\n",
+		},
+		{
+			"let m = macro(q) { quote(unquote(q)) }; m(1).y;",
+			func(node ast.Node) ast.Node {
+				if call, ok := node.(*ast.CallExpression); ok {
+					return call.Arguments[0]
+				}
+				return node
+			},
+			"let m = macro(q) {\n    q;\n};\n1.y;\n",
+		},
+		{
+			"let f = fn(a) { a }; f;",
+			func(node ast.Node) ast.Node {
+				if _, ok := node.(*ast.Identifier); ok {
+					return &ast.StringLiteral{Value: "id"}
+				}
+				return node
+			},
+			"let f = fn(a) {\n    \"id\";\n};\n\"id\";\n",
+		},
+		{
+			"if (x) { 1 } else { 2 }; while (y) { 3; 4 }",
+			func(node ast.Node) ast.Node {
+				switch node.(type) {
+				case *ast.BlockStatement:
+					return &ast.ExpressionStatement{}
+				case *ast.ExpressionStatement:
+					return &ast.IntegerLiteral{Value: 0}
+				}
+				return node
+			},
+			"if (x) {\n    1;\n} else {\n    2;\n}\n" +
+				"while (y) {\n    3;\n    4;\n}\n",
+		},
+	}
+
+	for _, s := range setup {
+		program := parse(t, s.input)
+		before := ast.Format(program)
+		actual := ast.Format(ast.Rewrite(program, s.fn))
+		if actual != s.expected {
+			t.Fatalf("rewrite mismatch. got=%q, expected=%q", actual, s.expected)
+		}
+		if after := ast.Format(program); after != before {
+			t.Fatalf("original modified. got=%q, expected=%q", after, before)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package ast
+
+import "github.com/vincentlabelle/monkey/token"
+
+type Span struct {
+	From token.Position
+	To   token.Position
+}
+
+func (s Span) Pos() token.Position {
+	return s.From
+}
+
+func (s Span) End() token.Position {
+	return s.To
+}
@@ -0,0 +1,113 @@
+package ast
+
+import "maps"
+
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, child := range children(node) {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+func children(node Node) []Node {
+	switch n := node.(type) {
+	case *Program:
+		return statementNodes(n.Statements)
+	case *LetStatement:
+		return []Node{n.Name, n.Value}
+	case *ReturnStatement:
+		return optionalNodes(n.Value)
+	case *ExpressionStatement:
+		return []Node{n.Expression}
+	case *WhileStatement:
+		return []Node{n.Condition, n.Body}
+	case *ForStatement:
+		return []Node{n.Variable, n.Iterable, n.Body}
+	case *BlockStatement:
+		return statementNodes(n.Statements)
+	case *FunctionLiteral:
+		return append(identifierNodes(n.Parameters), n.Body)
+	case *MacroLiteral:
+		return append(identifierNodes(n.Parameters), n.Body)
+	case *ArrayLiteral:
+		return expressionNodes(n.Elements)
+	case *HashLiteral:
+		return pairNodes(n.Pairs)
+	case *PrefixExpression:
+		return []Node{n.Right}
+	case *InfixExpression:
+		return []Node{n.Left, n.Right}
+	case *AssignExpression:
+		return []Node{n.Target, n.Value}
+	case *IfExpression:
+		nodes := []Node{n.Condition, n.Consequence}
+		if n.Alternative != nil {
+			nodes = append(nodes, n.Alternative)
+		}
+		return nodes
+	case *CallExpression:
+		return append([]Node{n.Function}, expressionNodes(n.Arguments)...)
+	case *IndexExpression:
+		return []Node{n.Left, n.Index}
+	}
+	return nil
+}
+
+func optionalNodes(expression Expression) []Node {
+	if expression == nil {
+		return nil
+	}
+	return []Node{expression}
+}
+
+func statementNodes(statements []Statement) []Node {
+	nodes := make([]Node, len(statements))
+	for i, statement := range statements {
+		nodes[i] = statement
+	}
+	return nodes
+}
+
+func identifierNodes(identifiers []*Identifier) []Node {
+	nodes := make([]Node, len(identifiers))
+	for i, identifier := range identifiers {
+		nodes[i] = identifier
+	}
+	return nodes
+}
+
+func expressionNodes(expressions []Expression) []Node {
+	nodes := make([]Node, len(expressions))
+	for i, expression := range expressions {
+		nodes[i] = expression
+	}
+	return nodes
+}
+
+func pairNodes(pairs map[HashKey]Expression) []Node {
+	nodes := []Node{}
+	for _, key := range SortHashKeys(maps.Keys(pairs)) {
+		nodes = append(nodes, key.Expression, pairs[key])
+	}
+	return nodes
+}
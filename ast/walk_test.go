@@ -0,0 +1,135 @@
+package ast_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vincentlabelle/monkey/ast"
+)
+
+func TestInspect(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			"let f = fn(a, b) { return -a; };",
+			[]string{
+				"Program", "LetStatement", "Identifier f", "FunctionLiteral",
+				"Identifier a", "Identifier b", "BlockStatement",
+				"ReturnStatement", "PrefixExpression", "Identifier a",
+			},
+		},
+		{
+			"let m = macro(x) { x }; export let y = import \"y\";",
+			[]string{
+				"Program", "LetStatement", "Identifier m", "MacroLiteral",
+				"Identifier x", "BlockStatement", "ExpressionStatement",
+				"Identifier x", "LetStatement", "Identifier y",
+				"ImportExpression",
+			},
+		},
+		{
+			`{"b": [1.5, true], 2: "c"}[x = 1 + 2];`,
+			[]string{
+				"Program", "ExpressionStatement", "IndexExpression",
+				"HashLiteral", "StringLiteral b", "ArrayLiteral",
+				"FloatLiteral 1.5", "BooleanLiteral true", "IntegerLiteral 2",
+				"StringLiteral c", "AssignExpression", "Identifier x",
+				"InfixExpression", "IntegerLiteral 1", "IntegerLiteral 2",
+			},
+		},
+		{
+			"while (a) { for (i in b) { if (i) { break; } else { continue; } } }",
+			[]string{
+				"Program", "WhileStatement", "Identifier a", "BlockStatement",
+				"ForStatement", "Identifier i", "Identifier b",
+				"BlockStatement", "ExpressionStatement", "IfExpression",
+				"Identifier i", "BlockStatement", "BreakStatement",
+				"BlockStatement", "ContinueStatement",
+			},
+		},
+		{
+			"f(1, g(2));",
+			[]string{
+				"Program", "ExpressionStatement", "CallExpression",
+				"Identifier f", "IntegerLiteral 1", "CallExpression",
+				"Identifier g", "IntegerLiteral 2",
+			},
+		},
+	}
+
+	for _, s := range setup {
+		actual := []string{}
+		ast.Inspect(parse(t, s.input), func(node ast.Node) bool {
+			if node != nil {
+				actual = append(actual, describe(node))
+			}
+			return true
+		})
+		if !reflect.DeepEqual(actual, s.expected) {
+			t.Fatalf("nodes mismatch. got=%v, expected=%v", actual, s.expected)
+		}
+	}
+}
+
+func describe(node ast.Node) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	switch n := node.(type) {
+	case *ast.Identifier:
+		return name + " " + n.Value
+	case *ast.IntegerLiteral:
+		return fmt.Sprintf("%v %v", name, n.Value)
+	case *ast.FloatLiteral:
+		return fmt.Sprintf("%v %v", name, n.Value)
+	case *ast.BooleanLiteral:
+		return fmt.Sprintf("%v %v", name, n.Value)
+	case *ast.StringLiteral:
+		return name + " " + n.Value
+	}
+	return name
+}
+
+func TestInspectPrune(t *testing.T) {
+	program := parse(t, "let f = fn(x) { x + 1 }; f(2) + 3;")
+	count := 0
+	ast.Inspect(program, func(node ast.Node) bool {
+		if _, ok := node.(*ast.IntegerLiteral); ok {
+			count++
+		}
+		_, ok := node.(*ast.FunctionLiteral)
+		return !ok
+	})
+	if count != 2 {
+		t.Fatalf("number of integers mismatch. got=%v, expected=2", count)
+	}
+}
+
+type depthVisitor struct {
+	depth   *int
+	maximum *int
+}
+
+func (v depthVisitor) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		*v.depth--
+		return nil
+	}
+	*v.depth++
+	*v.maximum = max(*v.maximum, *v.depth)
+	return v
+}
+
+func TestWalk(t *testing.T) {
+	depth, maximum := 0, 0
+	program := parse(t, "let x = [1, [2, [3]]]; x;")
+	ast.Walk(depthVisitor{depth: &depth, maximum: &maximum}, program)
+	if depth != 0 {
+		t.Fatalf("depth mismatch. got=%v, expected=0", depth)
+	}
+	if maximum != 6 {
+		t.Fatalf("maximum depth mismatch. got=%v, expected=6", maximum)
+	}
+}
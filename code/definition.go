@@ -1,6 +1,9 @@
 package code
 
-import "log"
+import (
+	"fmt"
+	"log"
+)
 
 type Definition struct {
 	Name          string
@@ -40,6 +43,25 @@ var definitions = map[Opcode]*Definition{
 	OpReturn:         {"OpReturn", OpReturn, []int{}},
 	OpClosure:        {"OpClosure", OpClosure, []int{2, 1}},
 	OpCurrentClosure: {"OpCurrentClosure", OpCurrentClosure, []int{}},
+	OpIter:           {"OpIter", OpIter, []int{}},
+	OpSetFree:        {"OpSetFree", OpSetFree, []int{1}},
+	OpSetIndex:       {"OpSetIndex", OpSetIndex, []int{}},
+	OpCaptureLocal:   {"OpCaptureLocal", OpCaptureLocal, []int{2}},
+	OpCaptureFree:    {"OpCaptureFree", OpCaptureFree, []int{1}},
+	OpLowerEqual:     {"OpLowerEqual", OpLowerEqual, []int{}},
+	OpGreaterEqual:   {"OpGreaterEqual", OpGreaterEqual, []int{}},
+	OpMod:            {"OpMod", OpMod, []int{}},
+	OpPow:            {"OpPow", OpPow, []int{}},
+	OpBitAnd:         {"OpBitAnd", OpBitAnd, []int{}},
+	OpBitOr:          {"OpBitOr", OpBitOr, []int{}},
+	OpBitXor:         {"OpBitXor", OpBitXor, []int{}},
+	OpShiftLeft:      {"OpShiftLeft", OpShiftLeft, []int{}},
+	OpShiftRight:     {"OpShiftRight", OpShiftRight, []int{}},
+	OpBitNot:         {"OpBitNot", OpBitNot, []int{}},
+	OpImport:         {"OpImport", OpImport, []int{2, 2}},
+	OpDup:            {"OpDup", OpDup, []int{}},
+	OpWide:           {"OpWide", OpWide, []int{}},
+	OpQuote:          {"OpQuote", OpQuote, []int{2, 1}},
 }
 
 func Lookup(op byte) *Definition {
@@ -51,10 +73,50 @@ func Lookup(op byte) *Definition {
 	return def
 }
 
-func getWidth(def *Definition) int {
+func IsDefined(op byte) bool {
+	_, ok := definitions[Opcode(op)]
+	return ok
+}
+
+func Width(op Opcode) int {
+	return getWidth(Lookup(byte(op)), false)
+}
+
+func WideWidth(op Opcode) int {
+	return getWidth(Lookup(byte(op)), true)
+}
+
+func CanWiden(op Opcode) bool {
+	def, ok := definitions[op]
+	return ok && op != OpWide && len(def.OperandWidths) > 0
+}
+
+func getWidth(def *Definition, wide bool) int {
 	width := 1
-	for _, w := range def.OperandWidths {
+	if wide {
+		width++
+	}
+	for _, w := range getOperandWidths(def, wide) {
 		width += w
 	}
 	return width
 }
+
+func getOperandWidths(def *Definition, wide bool) []int {
+	if !wide {
+		return def.OperandWidths
+	}
+	widths := make([]int, len(def.OperandWidths))
+	for i, w := range def.OperandWidths {
+		widths[i] = 2 * w
+	}
+	return widths
+}
+
+func (op Opcode) String() string {
+	def, ok := definitions[op]
+	if !ok {
+		return fmt.Sprintf("Opcode(%d)", byte(op))
+	}
+	return def.Name
+}
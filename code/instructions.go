@@ -8,19 +8,19 @@ func (instructions Instructions) String() string {
 	s, i := "", 0
 	for i < len(instructions) {
 		op, operands, width := Unmake(instructions[i:])
-		s += cast(i, op, operands)
+		wide := Opcode(instructions[i]) == OpWide
+		s += cast(i, op, operands, wide)
 		i += width
 	}
 	return s
 }
 
-func cast(i int, op Opcode, operands []int) string {
-	return fmt.Sprintf(
-		"%04d %v%s\n",
-		i,
-		definitions[op].Name,
-		castOperands(operands),
-	)
+func cast(i int, op Opcode, operands []int, wide bool) string {
+	name := definitions[op].Name
+	if wide {
+		name = definitions[OpWide].Name + " " + name
+	}
+	return fmt.Sprintf("%04d %v%s\n", i, name, castOperands(operands))
 }
 
 func castOperands(operands []int) string {
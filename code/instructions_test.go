@@ -14,12 +14,16 @@ func TestInstructions(t *testing.T) {
 				Make(OpConstant, 2),
 				Make(OpConstant, 65535),
 				Make(OpClosure, 65535, 255),
+				Make(OpConstant, 65536),
+				Make(OpCall, 256),
 			},
 			"0000 OpAdd\n" +
 				"0001 OpCall 1\n" +
 				"0003 OpConstant 2\n" +
 				"0006 OpConstant 65535\n" +
-				"0009 OpClosure 65535 255\n",
+				"0009 OpClosure 65535 255\n" +
+				"0013 OpWide OpConstant 65536\n" +
+				"0019 OpWide OpCall 256\n",
 		},
 	}
 
@@ -14,38 +14,63 @@ func Make(op Opcode, operands ...int) []byte {
 }
 
 func makeInstruction(def *Definition, operands []int) []byte {
-	instruction := initializeInstruction(def)
-	return addOperands(instruction, def, operands)
+	wide := isWide(def, operands)
+	instruction := initializeInstruction(def, wide)
+	widths := getOperandWidths(def, wide)
+	return addOperands(instruction, widths, operands)
 }
 
-func initializeInstruction(def *Definition) []byte {
-	width := getWidth(def)
+func isWide(def *Definition, operands []int) bool {
+	for i, width := range def.OperandWidths {
+		if i < len(operands) && operands[i] >= 1<<(8*width) {
+			return true
+		}
+	}
+	return false
+}
+
+func initializeInstruction(def *Definition, wide bool) []byte {
+	width := getWidth(def, wide)
 	instruction := make([]byte, width)
-	instruction[0] = byte(def.Op)
+	if wide {
+		instruction[0] = byte(OpWide)
+		instruction[1] = byte(def.Op)
+	} else {
+		instruction[0] = byte(def.Op)
+	}
 	return instruction
 }
 
 func addOperands(
 	instruction []byte,
-	def *Definition,
+	widths []int,
 	operands []int,
 ) []byte {
-	if len(def.OperandWidths) != len(operands) {
+	if len(widths) != len(operands) {
 		message := "cannot make instruction; unexpected number of operands"
 		log.Fatal(message)
 	}
-	return innerAddOperands(instruction, def, operands)
+	return innerAddOperands(instruction, widths, operands)
 }
 
 func innerAddOperands(
 	instruction []byte,
-	def *Definition,
+	widths []int,
 	operands []int,
 ) []byte {
-	remain := instruction[1:]
+	remain := instruction[len(instruction)-sum(widths):]
 	for i := 0; i < len(operands); i++ {
-		width := def.OperandWidths[i]
+		width := widths[i]
+		if operands[i] < 0 || operands[i] >= 1<<(8*width) {
+			message := "cannot make instruction; operand is out of range"
+			log.Fatal(message)
+		}
 		switch width {
+		case 4:
+			binary.BigEndian.PutUint32(
+				remain,
+				uint32(operands[i]),
+			)
 		case 2:
 			binary.BigEndian.PutUint16(
 				remain,
@@ -61,3 +86,11 @@ func innerAddOperands(
 	}
 	return instruction
 }
+
+func sum(widths []int) int {
+	total := 0
+	for _, width := range widths {
+		total += width
+	}
+	return total
+}
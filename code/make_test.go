@@ -28,6 +28,26 @@ func TestMake(t *testing.T) {
 			[]int{65534, 255},
 			[]byte{byte(OpClosure), 255, 254, 255},
 		},
+		{
+			OpConstant,
+			[]int{65535},
+			[]byte{byte(OpConstant), 255, 255},
+		},
+		{
+			OpConstant,
+			[]int{65536},
+			[]byte{byte(OpWide), byte(OpConstant), 0, 1, 0, 0},
+		},
+		{
+			OpCall,
+			[]int{256},
+			[]byte{byte(OpWide), byte(OpCall), 1, 0},
+		},
+		{
+			OpClosure,
+			[]int{1, 256},
+			[]byte{byte(OpWide), byte(OpClosure), 0, 0, 0, 1, 1, 0},
+		},
 	}
 
 	for _, s := range setup {
@@ -3,11 +3,13 @@ package code
 var PrefixOperator = map[string]Opcode{
 	"-": OpMinus,
 	"!": OpBang,
+	"~": OpBitNot,
 }
 
 var PrefixOperatorReverse = map[Opcode]string{
-	OpMinus: "-",
-	OpBang:  "!",
+	OpMinus:  "-",
+	OpBang:   "!",
+	OpBitNot: "~",
 }
 
 var InfixOperator = map[string]Opcode{
@@ -15,19 +17,43 @@ var InfixOperator = map[string]Opcode{
 	"-":  OpSub,
 	"*":  OpMul,
 	"/":  OpDiv,
+	"%":  OpMod,
+	"**": OpPow,
 	"==": OpEqual,
 	"!=": OpNotEqual,
 	">":  OpGreaterThan,
 	"<":  OpLowerThan,
+	">=": OpGreaterEqual,
+	"<=": OpLowerEqual,
+	"&":  OpBitAnd,
+	"|":  OpBitOr,
+	"^":  OpBitXor,
+	"<<": OpShiftLeft,
+	">>": OpShiftRight,
 }
 
 var InfixOperatorReverse = map[Opcode]string{
-	OpAdd:         "+",
-	OpSub:         "-",
-	OpMul:         "*",
-	OpDiv:         "/",
-	OpEqual:       "==",
-	OpNotEqual:    "!=",
-	OpGreaterThan: ">",
-	OpLowerThan:   "<",
+	OpAdd:          "+",
+	OpSub:          "-",
+	OpMul:          "*",
+	OpDiv:          "/",
+	OpMod:          "%",
+	OpPow:          "**",
+	OpEqual:        "==",
+	OpNotEqual:     "!=",
+	OpGreaterThan:  ">",
+	OpLowerThan:    "<",
+	OpGreaterEqual: ">=",
+	OpLowerEqual:   "<=",
+	OpBitAnd:       "&",
+	OpBitOr:        "|",
+	OpBitXor:       "^",
+	OpShiftLeft:    "<<",
+	OpShiftRight:   ">>",
+}
+
+var JumpOperand = map[Opcode]int{
+	OpJump:   0,
+	OpJumpIf: 0,
+	OpImport: 1,
 }
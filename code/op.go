@@ -34,4 +34,23 @@ const (
 	OpReturn
 	OpClosure
 	OpCurrentClosure
+	OpIter
+	OpSetFree
+	OpSetIndex
+	OpCaptureLocal
+	OpCaptureFree
+	OpLowerEqual
+	OpGreaterEqual
+	OpMod
+	OpPow
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShiftLeft
+	OpShiftRight
+	OpBitNot
+	OpImport
+	OpDup
+	OpWide
+	OpQuote
 )
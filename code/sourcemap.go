@@ -0,0 +1,41 @@
+package code
+
+import (
+	"sort"
+
+	"github.com/vincentlabelle/monkey/token"
+)
+
+type SourceMap []Mapping
+
+type Mapping struct {
+	Offset int
+	Pos    token.Position
+}
+
+func (m SourceMap) Add(offset int, pos token.Position) SourceMap {
+	if len(m) > 0 && m[len(m)-1].Pos == pos {
+		return m
+	}
+	if len(m) > 0 && m[len(m)-1].Offset == offset {
+		return m[:len(m)-1].Add(offset, pos)
+	}
+	return append(m, Mapping{Offset: offset, Pos: pos})
+}
+
+func (m SourceMap) Lookup(offset int) token.Position {
+	i := sort.Search(len(m), func(i int) bool {
+		return m[i].Offset > offset
+	})
+	if i == 0 {
+		return token.Position{}
+	}
+	return m[i-1].Pos
+}
+
+func (m SourceMap) Truncate(offset int) SourceMap {
+	i := sort.Search(len(m), func(i int) bool {
+		return m[i].Offset >= offset
+	})
+	return m[:i]
+}
@@ -0,0 +1,59 @@
+package code
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/vincentlabelle/monkey/token"
+)
+
+func TestSourceMap(t *testing.T) {
+	first := token.Position{Line: 1, Column: 1}
+	second := token.Position{Line: 2, Column: 5, Offset: 12}
+	m := SourceMap{}.
+		Add(0, first).
+		Add(3, first).
+		Add(4, second).
+		Add(6, first).
+		Add(6, second).
+		Add(9, first)
+	expected := SourceMap{{0, first}, {4, second}, {9, first}}
+	if !slices.Equal(m, expected) {
+		t.Fatalf("source map mismatch. got=%v, expected=%v", m, expected)
+	}
+
+	setup := []struct {
+		offset   int
+		expected token.Position
+	}{
+		{0, first},
+		{3, first},
+		{4, second},
+		{8, second},
+		{9, first},
+		{100, first},
+	}
+	for _, s := range setup {
+		actual := m.Lookup(s.offset)
+		if actual != s.expected {
+			t.Fatalf(
+				"position mismatch. got=%v, expected=%v",
+				actual,
+				s.expected,
+			)
+		}
+	}
+	if actual := (SourceMap{}).Lookup(0); actual.IsValid() {
+		t.Fatalf("position mismatch. got=%v, expected=invalid", actual)
+	}
+
+	truncated := m.Truncate(5)
+	expected = SourceMap{{0, first}, {4, second}}
+	if !slices.Equal(truncated, expected) {
+		t.Fatalf(
+			"source map mismatch. got=%v, expected=%v",
+			truncated,
+			expected,
+		)
+	}
+}
@@ -14,10 +14,16 @@ func Unmake(instruction []byte) (Opcode, []int, int) {
 }
 
 func unmake(instruction []byte) (Opcode, []int, int) {
-	def := Lookup(instruction[0])
-	width := getWidth(def)
+	header, wide := 1, Opcode(instruction[0]) == OpWide
+	if wide {
+		header++
+		validateWidth(header, instruction)
+	}
+	def := Lookup(instruction[header-1])
+	width := getWidth(def, wide)
 	validateWidth(width, instruction)
-	operands := readOperands(def, instruction)
+	widths := getOperandWidths(def, wide)
+	operands := readOperands(widths, instruction[header:])
 	return def.Op, operands, width
 }
 
@@ -28,11 +34,12 @@ func validateWidth(width int, instruction []byte) {
 	}
 }
 
-func readOperands(def *Definition, instruction []byte) []int {
-	operands := make([]int, len(def.OperandWidths))
-	remain := instruction[1:]
-	for i, width := range def.OperandWidths {
+func readOperands(widths []int, remain []byte) []int {
+	operands := make([]int, len(widths))
+	for i, width := range widths {
 		switch width {
+		case 4:
+			operands[i] = int(binary.BigEndian.Uint32(remain))
 		case 2:
 			operands[i] = int(binary.BigEndian.Uint16(remain))
 		case 1:
@@ -12,6 +12,11 @@ func TestUnmake(t *testing.T) {
 		{OpAdd, []int{}, 1},
 		{OpCall, []int{255}, 2},
 		{OpClosure, []int{65535, 255}, 4},
+		{OpConstant, []int{65536}, 6},
+		{OpJump, []int{1<<32 - 1}, 6},
+		{OpCall, []int{256}, 4},
+		{OpGetFree, []int{65535}, 4},
+		{OpClosure, []int{65536, 0}, 8},
 	}
 
 	for _, s := range setup {
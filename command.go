@@ -0,0 +1,204 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/compiler"
+	"github.com/vincentlabelle/monkey/lexer"
+	"github.com/vincentlabelle/monkey/module"
+	"github.com/vincentlabelle/monkey/parser"
+	"github.com/vincentlabelle/monkey/repl"
+)
+
+const (
+	exitSuccess = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+const usage = `usage: monkey <command> [arguments]
+
+commands:
+    run [--engine=eval|vm] [--path=dirs] [-O=0|1|2] <file> [arguments...]
+    repl [--engine=eval|vm] [--path=dirs] [-O=0|1|2]
+    compile [--path=dirs] [-O=0|1|2] [-o=output] <file>
+    disasm [--path=dirs] [-O=0|1|2] <file>
+    fmt [--check|--write] <file...>
+
+Executing monkey without a command starts the REPL.
+
+Modules are searched next to the importing file, then in the directories
+of --path, which defaults to MONKEYPATH.
+
+A file compiled to bytecode (.mkc) by compile is run directly by the vm, so
+it cannot be combined with --engine=eval.
+
+The -O flag sets how much the vm engine optimizes the bytecode: 0 disables
+optimizations, 1 (the default) is safe and 2 is aggressive.
+`
+
+func run(args []string, in io.Reader, out io.Writer, errOut io.Writer) int {
+	if len(args) == 0 {
+		return runRepl(args, in, out, errOut)
+	}
+	switch args[0] {
+	case "run":
+		return runFile(args[1:], errOut)
+	case "repl":
+		return runRepl(args[1:], in, out, errOut)
+	case "compile":
+		return runCompile(args[1:], errOut)
+	case "disasm":
+		return runDisasm(args[1:], out, errOut)
+	case "fmt":
+		return runFormat(args[1:], out, errOut)
+	case "help", "-h", "--help":
+		fmt.Fprint(out, usage)
+		return exitSuccess
+	}
+	return fail(errOut, "unknown command %q", args[0])
+}
+
+func fail(errOut io.Writer, format string, a ...any) int {
+	fmt.Fprintf(errOut, "monkey: "+format+"\n", a...)
+	fmt.Fprint(errOut, usage)
+	return exitUsage
+}
+
+func runRepl(args []string, in io.Reader, out io.Writer, errOut io.Writer) int {
+	flags, opts := newFlagSet("repl")
+	flags.StringVar(&opts.engine, "engine", "eval", "")
+	if err := flags.Parse(args); err != nil {
+		return fail(errOut, "%v", err)
+	}
+	if flags.NArg() > 0 {
+		return fail(errOut, "unexpected argument %q", flags.Arg(0))
+	}
+	loader := newLoader(opts.paths + string(filepath.ListSeparator) + ".")
+	engine, err := newEngine(opts, loader)
+	if err != nil {
+		return fail(errOut, "%v", err)
+	}
+	fmt.Fprintln(out, "Hello! This is the Monkey programming language!")
+	fmt.Fprintln(out, "Feel free to type in commands.")
+	repl.Start(in, out, engine)
+	return exitSuccess
+}
+
+type options struct {
+	engine string
+	paths  string
+	level  int
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *options) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	opts := &options{}
+	flags.StringVar(&opts.paths, "path", os.Getenv("MONKEYPATH"), "")
+	flags.IntVar(&opts.level, "O", int(compiler.Default), "")
+	return flags, opts
+}
+
+func getLevel(opts *options) (compiler.Level, error) {
+	level := compiler.Level(opts.level)
+	if level < compiler.None || level > compiler.Aggressive {
+		return level, fmt.Errorf("unknown optimization level %v", opts.level)
+	}
+	return level, nil
+}
+
+func newEngine(opts *options, loader *module.Loader) (repl.Engine, error) {
+	level, err := getLevel(opts)
+	if err != nil {
+		return nil, err
+	}
+	engine, ok := repl.NewEngine(opts.engine, loader, level)
+	if !ok {
+		return nil, fmt.Errorf("unknown engine %q", opts.engine)
+	}
+	return engine, nil
+}
+
+func newLoader(paths string) *module.Loader {
+	dirs := []string{}
+	for _, dir := range filepath.SplitList(paths) {
+		dirs = append(dirs, toModuleName(dir))
+	}
+	return module.NewLoader(os.DirFS("/"), dirs...)
+}
+
+func toModuleName(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return filepath.ToSlash(path)
+}
+
+func runFile(args []string, errOut io.Writer) int {
+	flags, opts := newFlagSet("run")
+	flags.StringVar(&opts.engine, "engine", "eval", "")
+	if err := flags.Parse(args); err != nil {
+		return fail(errOut, "%v", err)
+	}
+	engine, err := newEngine(opts, newLoader(opts.paths))
+	if err != nil {
+		return fail(errOut, "%v", err)
+	}
+	if flags.NArg() == 0 {
+		return fail(errOut, "missing file to run")
+	}
+	arguments := flags.Args()[1:]
+	if filepath.Ext(flags.Arg(0)) == ".mkc" {
+		if opts.engine != "vm" && isSet(flags, "engine") {
+			return fail(errOut, "engine %q cannot run bytecode", opts.engine)
+		}
+		return runBytecode(flags.Arg(0), arguments, errOut)
+	}
+	engine.SetArguments(arguments)
+	return runProgram(flags.Arg(0), engine, errOut)
+}
+
+func isSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+func runProgram(
+	path string,
+	engine repl.Engine,
+	errOut io.Writer,
+) int {
+	program, ok := parseFile(path, errOut)
+	if !ok {
+		return exitFailure
+	}
+	if _, err := engine.Execute(program); err != nil {
+		fmt.Fprintln(errOut, repl.ErrorKind(err)+": "+err.Error())
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+func parseFile(path string, errOut io.Writer) (*ast.Program, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(errOut, "monkey: "+err.Error())
+		return nil, false
+	}
+	lex := lexer.NewFile(toModuleName(path), string(content))
+	p := parser.New(lex)
+	program, errors := p.ParseProgram()
+	for _, err := range errors {
+		fmt.Fprintln(errOut, "syntax error: "+err.Error())
+	}
+	return program, len(errors) == 0
+}
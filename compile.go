@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vincentlabelle/monkey/compiler"
+	"github.com/vincentlabelle/monkey/disasm"
+	"github.com/vincentlabelle/monkey/repl"
+	"github.com/vincentlabelle/monkey/vm"
+)
+
+func runCompile(args []string, errOut io.Writer) int {
+	flags, opts := newFlagSet("compile")
+	output := flags.String("o", "", "")
+	if err := flags.Parse(args); err != nil {
+		return fail(errOut, "%v", err)
+	}
+	level, err := getLevel(opts)
+	if err != nil {
+		return fail(errOut, "%v", err)
+	}
+	if flags.NArg() == 0 {
+		return fail(errOut, "missing file to compile")
+	}
+	if flags.NArg() > 1 {
+		return fail(errOut, "unexpected argument %q", flags.Arg(1))
+	}
+	path := flags.Arg(0)
+	if *output == "" {
+		*output = strings.TrimSuffix(path, filepath.Ext(path)) + ".mkc"
+	}
+	machine := repl.NewMachine(newLoader(opts.paths), level)
+	return compileFile(path, *output, machine, errOut)
+}
+
+func compileFile(
+	path string,
+	output string,
+	machine *repl.Machine,
+	errOut io.Writer,
+) int {
+	bytecode, ok := compileSource(path, machine, errOut)
+	if !ok {
+		return exitFailure
+	}
+	data, err := bytecode.MarshalBinary()
+	if err == nil {
+		err = os.WriteFile(output, data, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintln(errOut, "monkey: "+err.Error())
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+func compileSource(
+	path string,
+	machine *repl.Machine,
+	errOut io.Writer,
+) (*compiler.Bytecode, bool) {
+	program, ok := parseFile(path, errOut)
+	if !ok {
+		return nil, false
+	}
+	bytecode, err := machine.Compile(program)
+	if err != nil {
+		fmt.Fprintln(errOut, repl.ErrorKind(err)+": "+err.Error())
+		return nil, false
+	}
+	return bytecode, true
+}
+
+func runBytecode(path string, arguments []string, errOut io.Writer) int {
+	bytecode, ok := readBytecode(path, errOut)
+	if !ok {
+		return exitFailure
+	}
+	machine := vm.New(bytecode)
+	machine.SetArguments(arguments)
+	if err := machine.Run(); err != nil {
+		fmt.Fprintln(errOut, repl.ErrorKind(err)+": "+err.Error())
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+func readBytecode(path string, errOut io.Writer) (*compiler.Bytecode, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(errOut, "monkey: "+err.Error())
+		return nil, false
+	}
+	bytecode, err := vm.Load(data)
+	if err != nil {
+		fmt.Fprintln(errOut, "monkey: "+path+": "+err.Error())
+		return nil, false
+	}
+	return bytecode, true
+}
+
+func runDisasm(args []string, out io.Writer, errOut io.Writer) int {
+	flags, opts := newFlagSet("disasm")
+	if err := flags.Parse(args); err != nil {
+		return fail(errOut, "%v", err)
+	}
+	level, err := getLevel(opts)
+	if err != nil {
+		return fail(errOut, "%v", err)
+	}
+	if flags.NArg() == 0 {
+		return fail(errOut, "missing file to disassemble")
+	}
+	if flags.NArg() > 1 {
+		return fail(errOut, "unexpected argument %q", flags.Arg(1))
+	}
+	machine := repl.NewMachine(newLoader(opts.paths), level)
+	bytecode, ok := loadBytecode(flags.Arg(0), machine, errOut)
+	if !ok {
+		return exitFailure
+	}
+	fmt.Fprint(out, disasm.Disassemble(bytecode, os.ReadFile))
+	return exitSuccess
+}
+
+func loadBytecode(
+	path string,
+	machine *repl.Machine,
+	errOut io.Writer,
+) (*compiler.Bytecode, bool) {
+	if filepath.Ext(path) == ".mkc" {
+		return readBytecode(path, errOut)
+	}
+	return compileSource(path, machine, errOut)
+}
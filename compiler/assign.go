@@ -0,0 +1,50 @@
+package compiler
+
+import (
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/symbol"
+)
+
+func (c *Compiler) compileAssignExpression(expression *ast.AssignExpression) {
+	switch target := expression.Target.(type) {
+	case *ast.Identifier:
+		c.compileAssignIdentifier(target, expression.Value)
+	case *ast.IndexExpression:
+		c.compileAssignIndex(target, expression.Value)
+	default:
+		fail(target, "encountered unexpected assignment target")
+	}
+}
+
+func (c *Compiler) compileAssignIdentifier(
+	target *ast.Identifier,
+	value ast.Expression,
+) {
+	sym := c.resolveAssignable(target)
+	c.compileExpression(value)
+	c.storeSymbol(sym)
+	c.loadSymbol(sym)
+}
+
+func (c *Compiler) resolveAssignable(target *ast.Identifier) symbol.Symbol {
+	sym, ok := c.symbolTable.Resolve(target.Value)
+	if !ok {
+		fail(target, "cannot assign to undefined identifier %v", target.Value)
+	}
+	switch sym.Scope {
+	case symbol.BuiltinScope:
+		fail(target, "cannot assign to built-in %v", target.Value)
+	}
+	return sym
+}
+
+func (c *Compiler) compileAssignIndex(
+	target *ast.IndexExpression,
+	value ast.Expression,
+) {
+	c.compileExpression(target.Left)
+	c.compileExpression(target.Index)
+	c.compileExpression(value)
+	c.emit(code.OpSetIndex)
+}
@@ -1,34 +1,82 @@
 package compiler
 
 import (
-	"log"
 	"maps"
 
 	"github.com/vincentlabelle/monkey/ast"
 	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/evaluator"
+	"github.com/vincentlabelle/monkey/module"
 	"github.com/vincentlabelle/monkey/object"
 	"github.com/vincentlabelle/monkey/symbol"
+	"github.com/vincentlabelle/monkey/token"
+)
+
+const GlobalsSize = 65536
+
+type Level int
+
+const (
+	None Level = iota
+	Default
+	Aggressive
 )
 
 type Compiler struct {
-	scopes      []code.Instructions
+	scopes      []*scope
 	scopeIndex  int
 	constants   []object.Object
+	indexes     map[constantKey]int
+	folds       map[ast.Expression]object.Object
+	level       Level
 	symbolTable *symbol.SymbolTable
+	globals     *symbol.SymbolTable
+	loader      *module.Loader
+	modules     map[string]int
+	importing   []string
+	position    token.Position
 }
 
 func New() *Compiler {
+	return NewWithState(symbol.NewTable(), []object.Object{})
+}
+
+func NewWithState(
+	table *symbol.SymbolTable,
+	constants []object.Object,
+) *Compiler {
 	return &Compiler{
-		scopes:      []code.Instructions{{}},
-		constants:   []object.Object{},
-		symbolTable: symbol.NewTable(),
+		scopes:      []*scope{newScope()},
+		constants:   constants,
+		indexes:     indexConstants(constants),
+		folds:       map[ast.Expression]object.Object{},
+		level:       Default,
+		symbolTable: table,
+		globals:     table,
+		modules:     map[string]int{},
+		importing:   []string{},
 	}
 }
 
-func (c *Compiler) Compile(program *ast.Program) *Bytecode {
+func (c *Compiler) SetLoader(loader *module.Loader) {
+	c.loader = loader
+}
+
+func (c *Compiler) SetLevel(level Level) {
+	c.level = level
+}
+
+func (c *Compiler) Compile(
+	program *ast.Program,
+) (bytecode *Bytecode, err error) {
+	defer recoverError(&err)
 	c.compileStatements(program.Statements)
-	instructions := c.currentInstructions()
-	return &Bytecode{Instructions: instructions, Constants: c.constants}
+	c.optimize(c.currentScope())
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Constants:    c.constants,
+		SourceMap:    c.currentScope().sourceMap,
+	}, nil
 }
 
 func (c *Compiler) enterScope() {
@@ -37,7 +85,7 @@ func (c *Compiler) enterScope() {
 }
 
 func (c *Compiler) innerEnterScope() {
-	c.scopes = append(c.scopes, code.Instructions{})
+	c.scopes = append(c.scopes, newScope())
 	c.scopeIndex++
 }
 
@@ -46,23 +94,44 @@ func (c *Compiler) enterSymbolTable() {
 }
 
 func (c *Compiler) compileStatements(statements []ast.Statement) int {
-	var pos int
+	pos := -1
 	for _, statement := range statements {
-		switch s := statement.(type) {
-		case *ast.ExpressionStatement:
-			pos = c.compileExpressionStatement(s)
-		case *ast.LetStatement:
-			pos = c.compileLetStatement(s)
-		case *ast.ReturnStatement:
-			pos = c.compileReturnStatement(s)
-		default:
-			message := "cannot compile; encountered unexpected statement type"
-			log.Fatal(message)
-		}
+		pos = c.compileStatement(statement)
 	}
 	return pos
 }
 
+func (c *Compiler) compileStatement(statement ast.Statement) int {
+	previous := c.setPosition(statement.Pos())
+	defer c.setPosition(previous)
+	pos := -1
+	switch s := statement.(type) {
+	case *ast.ExpressionStatement:
+		pos = c.compileExpressionStatement(s)
+	case *ast.LetStatement:
+		pos = c.compileLetStatement(s)
+	case *ast.ReturnStatement:
+		pos = c.compileReturnStatement(s)
+	case *ast.WhileStatement:
+		pos = c.compileWhileStatement(s)
+	case *ast.ForStatement:
+		pos = c.compileForStatement(s)
+	case *ast.BreakStatement:
+		pos = c.compileBreakStatement(s)
+	case *ast.ContinueStatement:
+		pos = c.compileContinueStatement(s)
+	default:
+		fail(s, "encountered unexpected statement type")
+	}
+	return pos
+}
+
+func (c *Compiler) setPosition(pos token.Position) token.Position {
+	previous := c.position
+	c.position = pos
+	return previous
+}
+
 func (c *Compiler) compileExpressionStatement(
 	statement *ast.ExpressionStatement,
 ) int {
@@ -71,9 +140,13 @@ func (c *Compiler) compileExpressionStatement(
 }
 
 func (c *Compiler) compileExpression(expression ast.Expression) {
+	previous := c.setPosition(expression.Pos())
+	defer c.setPosition(previous)
 	switch e := expression.(type) {
 	case *ast.IntegerLiteral:
 		c.compileIntegerLiteral(e)
+	case *ast.FloatLiteral:
+		c.compileFloatLiteral(e)
 	case *ast.BooleanLiteral:
 		c.compileBooleanLiteral(e)
 	case *ast.StringLiteral:
@@ -96,9 +169,14 @@ func (c *Compiler) compileExpression(expression ast.Expression) {
 		c.compileFunctionLiteral(e)
 	case *ast.CallExpression:
 		c.compileCallExpression(e)
+	case *ast.AssignExpression:
+		c.compileAssignExpression(e)
+	case *ast.ImportExpression:
+		c.compileImportExpression(e)
+	case *ast.MacroLiteral:
+		fail(e, "macro must be bound by a top-level let statement")
 	default:
-		message := "cannot compile; encountered unexpected expression type"
-		log.Fatal(message)
+		fail(e, "encountered unexpected expression type")
 	}
 }
 
@@ -107,14 +185,26 @@ func (c *Compiler) compileIntegerLiteral(expression *ast.IntegerLiteral) {
 	c.compileConstant(obj)
 }
 
+func (c *Compiler) compileFloatLiteral(expression *ast.FloatLiteral) {
+	obj := object.NativeToFloat(expression.Value)
+	c.compileConstant(obj)
+}
+
 func (c *Compiler) compileConstant(obj object.Object) {
 	pos := c.addConstant(obj)
 	c.emit(code.OpConstant, pos)
 }
 
 func (c *Compiler) addConstant(obj object.Object) int {
+	key, ok := getConstantKey(obj)
+	if pos, found := c.indexes[key]; ok && found {
+		return pos
+	}
 	pos := len(c.constants)
 	c.constants = append(c.constants, obj)
+	if ok {
+		c.indexes[key] = pos
+	}
 	return pos
 }
 
@@ -127,21 +217,31 @@ func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 func (c *Compiler) addInstruction(instruction []byte) int {
 	instructions := c.currentInstructions()
 	pos := len(instructions)
+	scope := c.currentScope()
+	scope.sourceMap = scope.sourceMap.Add(pos, c.position)
 	instructions = append(instructions, instruction...)
 	c.updateCurrentInstructions(instructions)
 	return pos
 }
 
-func (c *Compiler) currentInstructions() code.Instructions {
+func (c *Compiler) currentScope() *scope {
 	return c.scopes[c.scopeIndex]
 }
 
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.currentScope().instructions
+}
+
 func (c *Compiler) updateCurrentInstructions(instructions code.Instructions) {
-	c.scopes[c.scopeIndex] = instructions
+	c.currentScope().instructions = instructions
 }
 
 func (c *Compiler) compileBooleanLiteral(expression *ast.BooleanLiteral) {
-	if expression.Value {
+	c.compileBoolean(expression.Value)
+}
+
+func (c *Compiler) compileBoolean(value bool) {
+	if value {
 		c.emit(code.OpTrue)
 	} else {
 		c.emit(code.OpFalse)
@@ -154,37 +254,56 @@ func (c *Compiler) compileStringLiteral(expression *ast.StringLiteral) {
 }
 
 func (c *Compiler) compileInfixExpression(expression *ast.InfixExpression) {
+	switch expression.Operator {
+	case "&&":
+		c.compileAndExpression(expression)
+	case "||":
+		c.compileOrExpression(expression)
+	default:
+		c.compileArithmeticExpression(expression)
+	}
+}
+
+func (c *Compiler) compileArithmeticExpression(
+	expression *ast.InfixExpression,
+) {
+	if c.compileFolded(expression) {
+		return
+	}
 	c.compileExpression(expression.Left)
 	c.compileExpression(expression.Right)
-	c.compileInfixOperator(expression.Operator)
+	c.compileInfixOperator(expression)
 }
 
-func (c *Compiler) compileInfixOperator(operator string) {
-	op, ok := code.InfixOperator[operator]
+func (c *Compiler) compileInfixOperator(expression *ast.InfixExpression) {
+	op, ok := code.InfixOperator[expression.Operator]
 	if !ok {
-		message := "cannot compile; encountered unexpected infix operator"
-		log.Fatal(message)
+		message := "encountered unexpected infix operator %v"
+		fail(expression, message, expression.Operator)
 	}
 	c.emit(op)
 }
 
 func (c *Compiler) compilePrefixExpression(expression *ast.PrefixExpression) {
+	if c.compileFolded(expression) {
+		return
+	}
 	c.compileExpression(expression.Right)
-	c.compilePrefixOperator(expression.Operator)
+	c.compilePrefixOperator(expression)
 }
 
-func (c *Compiler) compilePrefixOperator(operator string) {
-	op, ok := code.PrefixOperator[operator]
+func (c *Compiler) compilePrefixOperator(expression *ast.PrefixExpression) {
+	op, ok := code.PrefixOperator[expression.Operator]
 	if !ok {
-		message := "cannot compile; encountered unexpected prefix operator"
-		log.Fatal(message)
+		message := "encountered unexpected prefix operator %v"
+		fail(expression, message, expression.Operator)
 	}
 	c.emit(op)
 }
 
 func (c *Compiler) compileIfExpression(expression *ast.IfExpression) {
 	jumpIfPos := c.compileIfCondition(expression.Condition)
-	c.compileBlockStatement(expression.Consequence)
+	c.compileIfBlock(expression.Consequence)
 	jumpPos := c.compileIfAlternative(expression, jumpIfPos)
 	c.changeJumpOperand(jumpPos)
 }
@@ -194,17 +313,25 @@ func (c *Compiler) compileIfCondition(expression ast.Expression) int {
 	return c.emit(code.OpJumpIf, 9999) // 9999 to replace
 }
 
-func (c *Compiler) compileBlockStatement(statement *ast.BlockStatement) bool {
+func (c *Compiler) compileIfBlock(statement *ast.BlockStatement) {
+	if _, truncated := c.compileBlockStatement(statement); !truncated {
+		c.emit(code.OpNull)
+	}
+}
+
+func (c *Compiler) compileBlockStatement(
+	statement *ast.BlockStatement,
+) (int, bool) {
 	pos := c.compileStatements(statement.Statements)
-	if c.isOpPop(pos) {
+	if c.isOpcode(pos, code.OpPop) {
 		c.truncateInstructions(pos)
-		return true
+		return pos, true
 	}
-	return false
+	return pos, false
 }
 
-func (c *Compiler) isOpPop(pos int) bool {
-	return c.atOpcode(pos) == code.OpPop
+func (c *Compiler) isOpcode(pos int, op code.Opcode) bool {
+	return pos >= 0 && c.atOpcode(pos) == op
 }
 
 func (c *Compiler) atOpcode(pos int) code.Opcode {
@@ -215,6 +342,8 @@ func (c *Compiler) atOpcode(pos int) code.Opcode {
 func (c *Compiler) truncateInstructions(pos int) {
 	instructions := c.currentInstructions()
 	c.updateCurrentInstructions(instructions[:pos])
+	scope := c.currentScope()
+	scope.sourceMap = scope.sourceMap.Truncate(pos)
 }
 
 func (c *Compiler) compileIfAlternative(
@@ -229,16 +358,24 @@ func (c *Compiler) compileIfAlternative(
 
 func (c *Compiler) innerCompileIfAlternative(statement *ast.BlockStatement) {
 	if statement != nil {
-		c.compileBlockStatement(statement)
+		c.compileIfBlock(statement)
 	} else {
 		c.emit(code.OpNull)
 	}
 }
 
 func (c *Compiler) changeJumpOperand(pos int) {
-	op := c.atOpcode(pos)
-	operand := len(c.currentInstructions())
-	instruction := code.Make(op, operand)
+	c.changeJumpOperandTo(pos, len(c.currentInstructions()))
+}
+
+func (c *Compiler) changeJumpOperandTo(pos int, operand int) {
+	op, operands, width := code.Unmake(c.currentInstructions()[pos:])
+	operands[code.JumpOperand[op]] = operand
+	instruction := code.Make(op, operands...)
+	if len(instruction) != width {
+		c.currentScope().jumps[pos] = operand // Relocated when optimized
+		return
+	}
 	c.replaceInstruction(pos, instruction)
 }
 
@@ -255,8 +392,7 @@ func (c *Compiler) compileIdentifier(expression *ast.Identifier) {
 func (c *Compiler) resolveSymbol(expression *ast.Identifier) symbol.Symbol {
 	sym, ok := c.symbolTable.Resolve(expression.Value)
 	if !ok {
-		message := "cannot compile; encountered symbol which cannot be resolved"
-		log.Fatal(message)
+		fail(expression, "encountered undefined identifier %v", expression.Value)
 	}
 	return sym
 }
@@ -309,18 +445,20 @@ func (c *Compiler) compileIndexExpression(expression *ast.IndexExpression) {
 }
 
 func (c *Compiler) compileFunctionLiteral(expression *ast.FunctionLiteral) {
-	instructions, count, free := c.innerCompileFunctionLiteral(expression)
+	scope, count, free := c.innerCompileFunctionLiteral(expression)
 	obj := &object.CompiledFunction{
-		Instructions:  instructions,
+		Name:          expression.Name,
+		Instructions:  scope.instructions,
 		NumLocals:     count,
 		NumParameters: len(expression.Parameters),
+		SourceMap:     scope.sourceMap,
 	}
 	c.compileClosure(obj, free)
 }
 
 func (c *Compiler) innerCompileFunctionLiteral(
 	expression *ast.FunctionLiteral,
-) (code.Instructions, int, []symbol.Symbol) {
+) (*scope, int, []symbol.Symbol) {
 	c.enterScope()
 	c.defineFunctionName(expression)
 	c.compileFunctionParameters(expression.Parameters)
@@ -329,11 +467,28 @@ func (c *Compiler) innerCompileFunctionLiteral(
 }
 
 func (c *Compiler) defineFunctionName(expression *ast.FunctionLiteral) {
-	if expression.Name != "" {
+	if expression.Name != "" && !assignsName(expression) {
 		c.symbolTable.DefineFunctionName(expression.Name)
 	}
 }
 
+func assignsName(expression *ast.FunctionLiteral) bool {
+	found := false
+	ast.Inspect(expression.Body, func(node ast.Node) bool {
+		assign, ok := node.(*ast.AssignExpression)
+		if ok && isIdentifier(assign.Target, expression.Name) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func isIdentifier(expression ast.Expression, name string) bool {
+	identifier, ok := expression.(*ast.Identifier)
+	return ok && identifier.Value == name
+}
+
 func (c *Compiler) compileFunctionParameters(expressions []*ast.Identifier) {
 	for _, expression := range expressions {
 		c.defineSymbol(expression)
@@ -351,23 +506,29 @@ func (c *Compiler) compileFunctionBody(
 }
 
 func (c *Compiler) compileNonEmptyFunctionBody(statement *ast.BlockStatement) {
-	truncated := c.compileBlockStatement(statement)
+	pos, truncated := c.compileBlockStatement(statement)
+	last := statement.Statements[len(statement.Statements)-1]
+	previous := c.setPosition(last.Pos())
+	defer c.setPosition(previous)
 	if truncated {
 		c.emit(code.OpReturnValue)
+	} else if !c.isOpcode(pos, code.OpReturnValue) {
+		c.emit(code.OpReturn)
 	}
 }
 
-func (c *Compiler) leaveScope() (code.Instructions, int, []symbol.Symbol) {
-	instructions := c.innerLeaveScope()
+func (c *Compiler) leaveScope() (*scope, int, []symbol.Symbol) {
+	scope := c.innerLeaveScope()
+	c.optimize(scope)
 	count, free := c.leaveSymbolTable()
-	return instructions, count, free
+	return scope, count, free
 }
 
-func (c *Compiler) innerLeaveScope() code.Instructions {
-	instructions := c.currentInstructions()
+func (c *Compiler) innerLeaveScope() *scope {
+	scope := c.currentScope()
 	c.scopes = c.scopes[:c.scopeIndex]
 	c.scopeIndex--
-	return instructions
+	return scope
 }
 
 func (c *Compiler) leaveSymbolTable() (int, []symbol.Symbol) {
@@ -378,7 +539,7 @@ func (c *Compiler) leaveSymbolTable() (int, []symbol.Symbol) {
 }
 
 func (c *Compiler) compileClosure(obj object.Object, free []symbol.Symbol) {
-	c.loadSymbols(free)
+	c.captureSymbols(free)
 	pos := c.addConstant(obj)
 	c.emit(code.OpClosure, pos, len(free))
 }
@@ -389,28 +550,61 @@ func (c *Compiler) loadSymbols(symbols []symbol.Symbol) {
 	}
 }
 
+func (c *Compiler) captureSymbols(symbols []symbol.Symbol) {
+	for _, sym := range symbols {
+		c.captureSymbol(sym)
+	}
+}
+
+func (c *Compiler) captureSymbol(sym symbol.Symbol) {
+	if sym.Scope == symbol.FreeScope {
+		c.emit(code.OpCaptureFree, sym.Index)
+	} else {
+		c.emit(code.OpCaptureLocal, sym.Index)
+	}
+}
+
 func (c *Compiler) compileCallExpression(expression *ast.CallExpression) {
+	if evaluator.IsQuoteCall(expression) {
+		c.compileQuote(expression)
+		return
+	}
 	c.compileExpression(expression.Function)
 	c.compileExpressions(expression.Arguments)
 	c.emit(code.OpCall, len(expression.Arguments))
 }
 
 func (c *Compiler) compileLetStatement(statement *ast.LetStatement) int {
-	sym := c.defineSymbol(statement.Name)
+	if fn, ok := statement.Value.(*ast.FunctionLiteral); ok && assignsName(fn) {
+		sym := c.defineSymbol(statement.Name)
+		c.compileExpression(statement.Value)
+		return c.storeSymbol(sym)
+	}
 	c.compileExpression(statement.Value)
-	op := c.getOpSet(sym)
-	return c.emit(op, sym.Index)
+	sym := c.defineSymbol(statement.Name)
+	return c.storeSymbol(sym)
 }
 
 func (c *Compiler) defineSymbol(expression *ast.Identifier) symbol.Symbol {
-	return c.symbolTable.Define(expression.Value)
+	return c.checkSymbol(c.symbolTable.Define(expression.Value))
+}
+
+func (c *Compiler) checkSymbol(sym symbol.Symbol) symbol.Symbol {
+	if sym.Scope == symbol.GlobalScope && sym.Index >= GlobalsSize {
+		failAt(c.position, "too many globals; limit is %v", GlobalsSize)
+	}
+	return sym
 }
 
 func (c *Compiler) getOpSet(sym symbol.Symbol) code.Opcode {
-	if sym.Scope == symbol.GlobalScope {
+	switch sym.Scope {
+	case symbol.GlobalScope:
 		return code.OpSetGlobal
+	case symbol.FreeScope:
+		return code.OpSetFree
+	default:
+		return code.OpSetLocal
 	}
-	return code.OpSetLocal
 }
 
 func (c *Compiler) compileReturnStatement(statement *ast.ReturnStatement) int {
@@ -1,6 +1,8 @@
 package compiler
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/vincentlabelle/monkey/ast"
@@ -8,6 +10,7 @@ import (
 	"github.com/vincentlabelle/monkey/lexer"
 	"github.com/vincentlabelle/monkey/object"
 	"github.com/vincentlabelle/monkey/parser"
+	"github.com/vincentlabelle/monkey/symbol"
 )
 
 func Test(t *testing.T) {
@@ -29,6 +32,19 @@ func Test(t *testing.T) {
 				&object.Integer{Value: 2},
 			},
 		},
+		{
+			`1.5 + 2;`,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Float{Value: 1.5},
+				&object.Integer{Value: 2},
+			},
+		},
 		{
 			`1 - 2;`,
 			[]code.Instructions{
@@ -392,8 +408,8 @@ func Test(t *testing.T) {
 				code.Make(code.OpConstant, 1),
 				code.Make(code.OpConstant, 2),
 				code.Make(code.OpArray, 3),
-				code.Make(code.OpConstant, 3),
-				code.Make(code.OpConstant, 4),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 0),
 				code.Make(code.OpAdd),
 				code.Make(code.OpIndex),
 				code.Make(code.OpPop),
@@ -402,8 +418,6 @@ func Test(t *testing.T) {
 				&object.Integer{Value: 1},
 				&object.Integer{Value: 2},
 				&object.Integer{Value: 3},
-				&object.Integer{Value: 1},
-				&object.Integer{Value: 1},
 			},
 		},
 		{
@@ -412,8 +426,8 @@ func Test(t *testing.T) {
 				code.Make(code.OpConstant, 0),
 				code.Make(code.OpConstant, 1),
 				code.Make(code.OpHash, 1),
-				code.Make(code.OpConstant, 2),
-				code.Make(code.OpConstant, 3),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 0),
 				code.Make(code.OpSub),
 				code.Make(code.OpIndex),
 				code.Make(code.OpPop),
@@ -421,8 +435,6 @@ func Test(t *testing.T) {
 			[]object.Object{
 				&object.Integer{Value: 1},
 				&object.Integer{Value: 2},
-				&object.Integer{Value: 2},
-				&object.Integer{Value: 1},
 			},
 		},
 		{
@@ -623,6 +635,208 @@ func Test(t *testing.T) {
 				},
 			},
 		},
+		{
+			`fn() { let a = 55; };`,
+			[]code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 55},
+				&object.CompiledFunction{
+					Instructions: code.Concatenate(
+						[]code.Instructions{
+							code.Make(code.OpConstant, 0),
+							code.Make(code.OpSetLocal, 0),
+							code.Make(code.OpReturn),
+						},
+					),
+					NumLocals:     1,
+					NumParameters: 0,
+				},
+			},
+		},
+		{
+			`1 <= 2 ** 3 % 4;`,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPow),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpMod),
+				code.Make(code.OpLowerEqual),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 2},
+				&object.Integer{Value: 3},
+				&object.Integer{Value: 4},
+			},
+		},
+		{
+			`~1 >> 2 >= 3;`,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpBitNot),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpShiftRight),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpGreaterEqual),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 2},
+				&object.Integer{Value: 3},
+			},
+		},
+		{
+			`true && false;`,
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpIf, 10),
+				code.Make(code.OpFalse),
+				code.Make(code.OpBang),
+				code.Make(code.OpBang),
+				code.Make(code.OpJump, 11),
+				code.Make(code.OpFalse),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{},
+		},
+		{
+			`true || false;`,
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpIf, 8),
+				code.Make(code.OpTrue),
+				code.Make(code.OpJump, 11),
+				code.Make(code.OpFalse),
+				code.Make(code.OpBang),
+				code.Make(code.OpBang),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{},
+		},
+		{
+			`let x = 1; x = 2;`,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 2},
+			},
+		},
+		{
+			`let a = [1]; a[0] = 2;`,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpSetIndex),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 0},
+				&object.Integer{Value: 2},
+			},
+		},
+		{
+			`fn(a) { fn() { a = 1; }; };`,
+			[]code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.CompiledFunction{
+					Instructions: code.Concatenate(
+						[]code.Instructions{
+							code.Make(code.OpConstant, 0),
+							code.Make(code.OpSetFree, 0),
+							code.Make(code.OpGetFree, 0),
+							code.Make(code.OpReturnValue),
+						},
+					),
+					NumLocals:     0,
+					NumParameters: 0,
+				},
+				&object.CompiledFunction{
+					Instructions: code.Concatenate(
+						[]code.Instructions{
+							code.Make(code.OpCaptureLocal, 0),
+							code.Make(code.OpClosure, 1, 1),
+							code.Make(code.OpReturnValue),
+						},
+					),
+					NumLocals:     1,
+					NumParameters: 1,
+				},
+			},
+		},
+		{
+			`while (true) { if (false) { continue; } break; }`,
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpIf, 23),
+				code.Make(code.OpFalse),
+				code.Make(code.OpJumpIf, 15),
+				code.Make(code.OpJump, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpJump, 16),
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+				code.Make(code.OpJump, 23),
+				code.Make(code.OpJump, 0),
+			},
+			[]object.Object{},
+		},
+		{
+			`for (x in [1]) { x; }`,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpIter),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpGetBuiltin, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpSetGlobal, 2),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpGetGlobal, 2),
+				code.Make(code.OpLowerThan),
+				code.Make(code.OpJumpIf, 63),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpIndex),
+				code.Make(code.OpSetGlobal, 3),
+				code.Make(code.OpGetGlobal, 3),
+				code.Make(code.OpPop),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpAdd),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpJump, 26),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 0},
+			},
+		},
 		{
 			`let oneArg = fn(a) { a }; oneArg(24);`,
 			[]code.Instructions{
@@ -739,7 +953,7 @@ func Test(t *testing.T) {
 				&object.CompiledFunction{
 					Instructions: code.Concatenate(
 						[]code.Instructions{
-							code.Make(code.OpGetLocal, 0),
+							code.Make(code.OpCaptureLocal, 0),
 							code.Make(code.OpClosure, 0, 1),
 							code.Make(code.OpReturnValue),
 						},
@@ -773,8 +987,8 @@ func Test(t *testing.T) {
 				&object.CompiledFunction{
 					Instructions: code.Concatenate(
 						[]code.Instructions{
-							code.Make(code.OpGetFree, 0),
-							code.Make(code.OpGetLocal, 0),
+							code.Make(code.OpCaptureFree, 0),
+							code.Make(code.OpCaptureLocal, 0),
 							code.Make(code.OpClosure, 0, 2),
 							code.Make(code.OpReturnValue),
 						},
@@ -785,7 +999,7 @@ func Test(t *testing.T) {
 				&object.CompiledFunction{
 					Instructions: code.Concatenate(
 						[]code.Instructions{
-							code.Make(code.OpGetLocal, 0),
+							code.Make(code.OpCaptureLocal, 0),
 							code.Make(code.OpClosure, 1, 1),
 							code.Make(code.OpReturnValue),
 						},
@@ -843,8 +1057,8 @@ func Test(t *testing.T) {
 						[]code.Instructions{
 							code.Make(code.OpConstant, 2),
 							code.Make(code.OpSetLocal, 0),
-							code.Make(code.OpGetFree, 0),
-							code.Make(code.OpGetLocal, 0),
+							code.Make(code.OpCaptureFree, 0),
+							code.Make(code.OpCaptureLocal, 0),
 							code.Make(code.OpClosure, 4, 2),
 							code.Make(code.OpReturnValue),
 						},
@@ -857,7 +1071,7 @@ func Test(t *testing.T) {
 						[]code.Instructions{
 							code.Make(code.OpConstant, 1),
 							code.Make(code.OpSetLocal, 0),
-							code.Make(code.OpGetLocal, 0),
+							code.Make(code.OpCaptureLocal, 0),
 							code.Make(code.OpClosure, 5, 1),
 							code.Make(code.OpReturnValue),
 						},
@@ -873,7 +1087,7 @@ func Test(t *testing.T) {
 				code.Make(code.OpClosure, 1, 0),
 				code.Make(code.OpSetGlobal, 0),
 				code.Make(code.OpGetGlobal, 0),
-				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 0),
 				code.Make(code.OpCall, 1),
 				code.Make(code.OpPop),
 			},
@@ -893,7 +1107,6 @@ func Test(t *testing.T) {
 					NumLocals:     1,
 					NumParameters: 1,
 				},
-				&object.Integer{Value: 1},
 			},
 		},
 		{
@@ -905,7 +1118,7 @@ func Test(t *testing.T) {
 			wrapper();
 			`,
 			[]code.Instructions{
-				code.Make(code.OpClosure, 3, 0),
+				code.Make(code.OpClosure, 2, 0),
 				code.Make(code.OpSetGlobal, 0),
 				code.Make(code.OpGetGlobal, 0),
 				code.Make(code.OpCall, 0),
@@ -927,14 +1140,13 @@ func Test(t *testing.T) {
 					NumLocals:     1,
 					NumParameters: 1,
 				},
-				&object.Integer{Value: 1},
 				&object.CompiledFunction{
 					Instructions: code.Concatenate(
 						[]code.Instructions{
 							code.Make(code.OpClosure, 1, 0),
 							code.Make(code.OpSetLocal, 0),
 							code.Make(code.OpGetLocal, 0),
-							code.Make(code.OpConstant, 2),
+							code.Make(code.OpConstant, 0),
 							code.Make(code.OpCall, 1),
 							code.Make(code.OpReturnValue),
 						},
@@ -947,22 +1159,343 @@ func Test(t *testing.T) {
 	}
 
 	for _, s := range setup {
-		actual := compile(s.input)
+		actual := compile(t, s.input, None)
 		expected := combine(s.expectedPieces, s.expectedConstants)
 		testBytecode(t, actual, expected)
 	}
 }
 
-func compile(input string) *Bytecode {
+func TestFold(t *testing.T) {
+	setup := []struct {
+		input             string
+		expectedPieces    []code.Instructions
+		expectedConstants []object.Object
+	}{
+		{
+			`1 + 2 * 3 ** 2; 7 / 2.0;`,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 19},
+				&object.Float{Value: 3.5},
+			},
+		},
+		{
+			`-5; !true; ~1; !!"a";`,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpFalse),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpTrue),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: -5},
+				&object.Integer{Value: -2},
+			},
+		},
+		{
+			`"mon" + "key"; 2 >= 1 + 1; 1.5 * 2 < 3;`,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpTrue),
+				code.Make(code.OpPop),
+				code.Make(code.OpFalse),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.String{Value: "monkey"},
+			},
+		},
+		{
+			`let x = 2; x * (1 + 1);`,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpMul),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 2},
+			},
+		},
+		{
+			`1 / 0; -"a"; 1 < true;`,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpDiv),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpMinus),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpTrue),
+				code.Make(code.OpLowerThan),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 0},
+				&object.String{Value: "a"},
+			},
+		},
+		{
+			`let x = "a"; 1; "a"; 1.0; 1; fn() { "a" + x };`,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpClosure, 3, 0),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.String{Value: "a"},
+				&object.Integer{Value: 1},
+				&object.Float{Value: 1},
+				&object.CompiledFunction{
+					Instructions: code.Concatenate(
+						[]code.Instructions{
+							code.Make(code.OpConstant, 0),
+							code.Make(code.OpGetGlobal, 0),
+							code.Make(code.OpAdd),
+							code.Make(code.OpReturnValue),
+						},
+					),
+				},
+			},
+		},
+	}
+
+	for _, s := range setup {
+		actual := compile(t, s.input, Default)
+		expected := combine(s.expectedPieces, s.expectedConstants)
+		testBytecode(t, actual, expected)
+	}
+}
+
+func TestFoldChain(t *testing.T) {
+	count := 100000
+	input := "let a = 1; a" + strings.Repeat(" + a", count-1) + ";"
+	actual := compile(t, input, Default)
+	expected := 3*2 + 3*count + (count - 1) + 1
+	if len(actual.Instructions) != expected {
+		t.Fatalf(
+			"instructions length mismatch. got=%v, expected=%v",
+			len(actual.Instructions),
+			expected,
+		)
+	}
+}
+
+func TestFoldQuote(t *testing.T) {
+	actual := compile(t, `quote(1 + 2);`, Default)
+	quote := actual.Constants[0].Inspect()
+	if quote != "quote(1 + 2)" {
+		t.Fatalf("quote mismatch. got=%v, expected=quote(1 + 2)", quote)
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	actual := compile(t, `let x = 1; quote(unquote(x) + unquote(2));`, None)
+	expected := code.Concatenate([]code.Instructions{
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpSetGlobal, 0),
+		code.Make(code.OpGetGlobal, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpQuote, 2, 2),
+		code.Make(code.OpPop),
+	})
+	testInstructions(t, actual.Instructions, expected)
+	quote := actual.Constants[2].Inspect()
+	if quote != "quote(unquote(x) + unquote(2))" {
+		t.Fatalf(
+			"quote mismatch. got=%v, expected=quote(unquote(x) + unquote(2))",
+			quote,
+		)
+	}
+}
+
+func TestForHiddenGlobals(t *testing.T) {
+	input := `
+	for (x in [1]) { for (y in [2]) { y; } };
+	for (x in [3]) { x; };
+	for (x in [4]) { x; };`
+	table := symbol.NewTable()
+	c := NewWithState(table, []object.Object{})
+	if _, err := c.Compile(parse(input)); err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	if table.CountDefinitions() != 8 {
+		t.Fatalf(
+			"definitions mismatch. got=%v, expected=8",
+			table.CountDefinitions(),
+		)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let a = 1;\nlet b = a + c;",
+			"2:13: cannot compile; encountered undefined identifier c",
+		},
+		{
+			`let a = a;`,
+			"1:9: cannot compile; encountered undefined identifier a",
+		},
+		{
+			`import "m.mk";`,
+			"1:1: cannot compile; imports aren't available",
+		},
+		{
+			`quote(unquote(1, 2));`,
+			"1:7: cannot compile; unquote expects exactly one argument; got=2",
+		},
+		{
+			`quote(unquote(unquote(1)));`,
+			"1:15: cannot compile; nested unquote isn't supported by compiler",
+		},
+		{
+			`let m = macro(x) { x };`,
+			"1:9: cannot compile; " +
+				"macro must be bound by a top-level let statement",
+		},
+		{
+			`x = 1;`,
+			"1:1: cannot compile; cannot assign to undefined identifier x",
+		},
+		{
+			`fn() { len = 1; };`,
+			"1:8: cannot compile; cannot assign to built-in len",
+		},
+		{
+			`fn() { let x = 1; fn() { y; }; };`,
+			"1:26: cannot compile; encountered undefined identifier y",
+		},
+	}
+
+	for _, s := range setup {
+		program := parse(s.input)
+		c := New()
+		_, err := c.Compile(program)
+		if err == nil {
+			t.Fatalf("expected error. expected=%q", s.expected)
+		}
+		if err.Error() != s.expected {
+			t.Fatalf(
+				"error mismatch. got=%q, expected=%q",
+				err.Error(),
+				s.expected,
+			)
+		}
+	}
+}
+
+func TestWideOperands(t *testing.T) {
+	setup := []struct {
+		count    int
+		expected []byte
+	}{
+		{
+			65536,
+			[]byte{byte(code.OpConstant), 255, 255, byte(code.OpPop)},
+		},
+		{
+			65537,
+			[]byte{
+				byte(code.OpWide), byte(code.OpConstant), 0, 1, 0, 0,
+				byte(code.OpPop),
+			},
+		},
+	}
+
+	for _, s := range setup {
+		input := sequence("%v;", s.count, "")
+		actual := compile(t, input, Default).Instructions
+		tail := actual[len(actual)-len(s.expected):]
+		testInstructions(t, tail, s.expected)
+	}
+}
+
+func TestWideJumps(t *testing.T) {
+	input := "let c = true; if (c) { " + sequence("%v;", 20000, "") + " };"
+	for _, level := range []Level{None, Default, Aggressive} {
+		instructions := compile(t, input, level).Instructions
+		offset := findOpcode(instructions, code.OpJumpIf)
+		op, operands, width := code.Unmake(instructions[offset:])
+		if op != code.OpJumpIf || width != 6 {
+			t.Fatalf(
+				"instruction mismatch at level %v. got=%v %v, expected=%v %v",
+				level,
+				op,
+				width,
+				code.OpJumpIf,
+				6,
+			)
+		}
+		target := operands[0]
+		jump, _, width := code.Unmake(instructions[target-6:])
+		if jump != code.OpJump || width != 6 {
+			t.Fatalf(
+				"instruction mismatch at level %v. got=%v %v, expected=%v %v",
+				level,
+				jump,
+				width,
+				code.OpJump,
+				6,
+			)
+		}
+		testInstructions(t, instructions[target:], code.Concatenate(
+			[]code.Instructions{code.Make(code.OpNull), code.Make(code.OpPop)},
+		))
+	}
+}
+
+func sequence(format string, count int, separator string) string {
+	pieces := make([]string, count)
+	for i := range pieces {
+		pieces[i] = fmt.Sprintf(format, i)
+	}
+	return strings.Join(pieces, separator)
+}
+
+func compile(t *testing.T, input string, level Level) *Bytecode {
 	program := parse(input)
 	c := New()
-	return c.Compile(program)
+	c.SetLevel(level)
+	bytecode, err := c.Compile(program)
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	return bytecode
 }
 
 func parse(input string) *ast.Program {
 	lex := lexer.New(input)
 	p := parser.New(lex)
-	return p.ParseProgram()
+	program, _ := p.ParseProgram()
+	return program
 }
 
 func combine(
@@ -1031,6 +1564,16 @@ func testObject(
 			)
 		}
 		testIntegerObject(t, a, e)
+	case *object.Float:
+		a, ok := actual.(*object.Float)
+		if !ok {
+			t.Fatalf(
+				"object type mismatch. got=%T, expected=%T",
+				actual,
+				expected,
+			)
+		}
+		testFloatObject(t, a, e)
 	case *object.String:
 		a, ok := actual.(*object.String)
 		if !ok {
@@ -1070,6 +1613,20 @@ func testIntegerObject(
 	}
 }
 
+func testFloatObject(
+	t *testing.T,
+	actual *object.Float,
+	expected *object.Float,
+) {
+	if actual.Value != expected.Value {
+		t.Fatalf(
+			"float value mismatch. got=%v, expected=%v",
+			actual.Value,
+			expected.Value,
+		)
+	}
+}
+
 func testStringObject(
 	t *testing.T,
 	actual *object.String,
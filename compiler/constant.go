@@ -0,0 +1,36 @@
+package compiler
+
+import (
+	"math"
+
+	"github.com/vincentlabelle/monkey/object"
+)
+
+type constantKey struct {
+	type_ string
+	value any
+}
+
+func getConstantKey(obj object.Object) (constantKey, bool) {
+	switch o := obj.(type) {
+	case *object.Integer:
+		return constantKey{type_: "Integer", value: o.Value}, true
+	case *object.Float:
+		return constantKey{type_: "Float", value: math.Float64bits(o.Value)}, true
+	case *object.String:
+		return constantKey{type_: "String", value: o.Value}, true
+	}
+	return constantKey{}, false
+}
+
+func indexConstants(constants []object.Object) map[constantKey]int {
+	indexes := map[constantKey]int{}
+	for i, obj := range constants {
+		if key, ok := getConstantKey(obj); ok {
+			if _, found := indexes[key]; !found {
+				indexes[key] = i
+			}
+		}
+	}
+	return indexes
+}
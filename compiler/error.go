@@ -0,0 +1,41 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/token"
+)
+
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Message
+	}
+	return e.Message
+}
+
+func fail(node ast.Node, format string, a ...any) {
+	failAt(node.Pos(), format, a...)
+}
+
+func failAt(pos token.Position, format string, a ...any) {
+	message := "cannot compile; " + fmt.Sprintf(format, a...)
+	panic(&Error{Pos: pos, Message: message})
+}
+
+func recoverError(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	e, ok := r.(*Error)
+	if !ok {
+		panic(r)
+	}
+	*err = e
+}
@@ -0,0 +1,84 @@
+package compiler
+
+import (
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/evaluator"
+	"github.com/vincentlabelle/monkey/object"
+)
+
+func (c *Compiler) compileFolded(expression ast.Expression) bool {
+	if c.level == None {
+		return false
+	}
+	obj, ok := c.fold(expression)
+	if !ok {
+		return false
+	}
+	switch o := obj.(type) {
+	case *object.Boolean:
+		c.compileBoolean(o.Value)
+	default:
+		c.compileConstant(obj)
+	}
+	return true
+}
+
+func (c *Compiler) fold(expression ast.Expression) (object.Object, bool) {
+	obj, ok := c.folds[expression]
+	if !ok {
+		obj = c.innerFold(expression)
+		c.folds[expression] = obj
+	}
+	return obj, isFoldable(obj)
+}
+
+func (c *Compiler) innerFold(expression ast.Expression) object.Object {
+	var obj object.Object
+	switch e := expression.(type) {
+	case *ast.IntegerLiteral:
+		obj = object.NativeToInteger(e.Value)
+	case *ast.FloatLiteral:
+		obj = object.NativeToFloat(e.Value)
+	case *ast.BooleanLiteral:
+		obj = object.NativeToBoolean(e.Value)
+	case *ast.StringLiteral:
+		obj = object.NativeToString(e.Value)
+	case *ast.PrefixExpression:
+		obj = c.foldPrefix(e)
+	case *ast.InfixExpression:
+		obj = c.foldInfix(e)
+	}
+	return obj
+}
+
+func (c *Compiler) foldPrefix(expression *ast.PrefixExpression) object.Object {
+	right, ok := c.fold(expression.Right)
+	if !ok {
+		return nil
+	}
+	return evaluator.EvalPrefix(expression.Operator, right)
+}
+
+func (c *Compiler) foldInfix(expression *ast.InfixExpression) object.Object {
+	if _, ok := code.InfixOperator[expression.Operator]; !ok {
+		return nil
+	}
+	left, ok := c.fold(expression.Left)
+	if !ok {
+		return nil
+	}
+	right, ok := c.fold(expression.Right)
+	if !ok {
+		return nil
+	}
+	return evaluator.EvalInfix(left, expression.Operator, right)
+}
+
+func isFoldable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.Float, *object.Boolean, *object.String:
+		return true
+	}
+	return false
+}
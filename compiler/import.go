@@ -0,0 +1,90 @@
+package compiler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/evaluator"
+	"github.com/vincentlabelle/monkey/object"
+	"github.com/vincentlabelle/monkey/symbol"
+	"github.com/vincentlabelle/monkey/token"
+)
+
+func (c *Compiler) compileImportExpression(expression *ast.ImportExpression) {
+	name := c.resolveModule(expression)
+	sym := c.checkSymbol(c.globals.Define(fmt.Sprintf("$module %v", name)))
+	pos := c.emit(code.OpImport, sym.Index, 9999) // 9999 to replace
+	index := c.compileModule(expression, name)
+	c.emit(code.OpClosure, index, 0)
+	c.emit(code.OpCall, 0)
+	c.emit(code.OpSetGlobal, sym.Index)
+	c.emit(code.OpGetGlobal, sym.Index)
+	c.changeJumpOperand(pos)
+}
+
+func (c *Compiler) resolveModule(expression *ast.ImportExpression) string {
+	if c.loader == nil {
+		fail(expression, "imports aren't available")
+	}
+	name, err := c.loader.Resolve(expression.Pos().File, expression.Path)
+	if err != nil {
+		fail(expression, "%v", err)
+	}
+	return name
+}
+
+func (c *Compiler) compileModule(
+	expression *ast.ImportExpression,
+	name string,
+) int {
+	if index, ok := c.modules[name]; ok {
+		return index
+	}
+	if slices.Contains(c.importing, name) {
+		cycle := strings.Join(append(slices.Clone(c.importing), name), " -> ")
+		fail(expression, "import cycle %v", cycle)
+	}
+	program, err := evaluator.LoadModule(c.loader, name)
+	if err != nil {
+		fail(expression, "%v", err)
+	}
+	c.importing = append(c.importing, name)
+	index := c.addConstant(c.compileModuleFunction(program))
+	c.importing = c.importing[:len(c.importing)-1]
+	c.modules[name] = index
+	return index
+}
+
+func (c *Compiler) compileModuleFunction(
+	program *ast.Program,
+) *object.CompiledFunction {
+	outer := c.symbolTable
+	previous := c.setPosition(token.Position{})
+	defer c.setPosition(previous)
+	c.innerEnterScope()
+	c.symbolTable = symbol.NewInnerTable(symbol.NewTable())
+	c.compileStatements(program.Statements)
+	c.compileExports(program)
+	scope, count, _ := c.leaveScope()
+	c.symbolTable = outer
+	return &object.CompiledFunction{
+		Name:         "<module>",
+		Instructions: scope.instructions,
+		NumLocals:    count,
+		SourceMap:    scope.sourceMap,
+	}
+}
+
+func (c *Compiler) compileExports(program *ast.Program) {
+	exports := evaluator.Exports(program)
+	for _, name := range exports {
+		c.setPosition(name.Pos())
+		c.compileConstant(object.NativeToString(name.Value))
+		c.compileIdentifier(name)
+	}
+	c.emit(code.OpHash, len(exports))
+	c.emit(code.OpReturnValue)
+}
@@ -0,0 +1,32 @@
+package compiler
+
+import (
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/code"
+)
+
+func (c *Compiler) compileAndExpression(expression *ast.InfixExpression) {
+	c.compileExpression(expression.Left)
+	jumpIfPos := c.emit(code.OpJumpIf, 9999) // 9999 to replace
+	c.compileTruthy(expression.Right)
+	jumpPos := c.emit(code.OpJump, 9999) // 9999 to replace
+	c.changeJumpOperand(jumpIfPos)
+	c.emit(code.OpFalse)
+	c.changeJumpOperand(jumpPos)
+}
+
+func (c *Compiler) compileOrExpression(expression *ast.InfixExpression) {
+	c.compileExpression(expression.Left)
+	jumpIfPos := c.emit(code.OpJumpIf, 9999) // 9999 to replace
+	c.emit(code.OpTrue)
+	jumpPos := c.emit(code.OpJump, 9999) // 9999 to replace
+	c.changeJumpOperand(jumpIfPos)
+	c.compileTruthy(expression.Right)
+	c.changeJumpOperand(jumpPos)
+}
+
+func (c *Compiler) compileTruthy(expression ast.Expression) {
+	c.compileExpression(expression)
+	c.emit(code.OpBang)
+	c.emit(code.OpBang)
+}
@@ -0,0 +1,123 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/object"
+	"github.com/vincentlabelle/monkey/symbol"
+)
+
+func (c *Compiler) compileWhileStatement(statement *ast.WhileStatement) int {
+	start := len(c.currentInstructions())
+	c.compileExpression(statement.Condition)
+	exit := c.emit(code.OpJumpIf, 9999) // 9999 to replace
+	c.compileLoopBody(statement.Body)
+	return c.closeLoop(start, start, exit)
+}
+
+func (c *Compiler) compileLoopBody(statement *ast.BlockStatement) {
+	scope := c.currentScope()
+	scope.loops = append(scope.loops, &loop{})
+	c.compileStatements(statement.Statements)
+}
+
+func (c *Compiler) closeLoop(start int, next int, exit int) int {
+	pos := c.emit(code.OpJump, start)
+	c.changeJumpOperand(exit)
+	l := c.leaveLoop()
+	for _, p := range l.breaks {
+		c.changeJumpOperand(p)
+	}
+	for _, p := range l.continues {
+		c.changeJumpOperandTo(p, next)
+	}
+	return pos
+}
+
+func (c *Compiler) leaveLoop() *loop {
+	scope := c.currentScope()
+	l := scope.loops[len(scope.loops)-1]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+	return l
+}
+
+func (c *Compiler) compileForStatement(statement *ast.ForStatement) int {
+	iterable, index, length := c.compileForHeader(statement.Iterable)
+	start := len(c.currentInstructions())
+	c.loadSymbols([]symbol.Symbol{index, length})
+	c.emit(code.OpLowerThan)
+	exit := c.emit(code.OpJumpIf, 9999) // 9999 to replace
+	c.loadSymbols([]symbol.Symbol{iterable, index})
+	c.emit(code.OpIndex)
+	c.storeSymbol(c.defineSymbol(statement.Variable))
+	c.compileLoopBody(statement.Body)
+	next := len(c.currentInstructions())
+	c.loadSymbol(index)
+	c.compileConstant(object.NativeToInteger(1))
+	c.emit(code.OpAdd)
+	c.storeSymbol(index)
+	return c.closeLoop(start, next, exit)
+}
+
+func (c *Compiler) compileForHeader(
+	expression ast.Expression,
+) (symbol.Symbol, symbol.Symbol, symbol.Symbol) {
+	iterable := c.defineHidden("iterable")
+	index := c.defineHidden("index")
+	length := c.defineHidden("length")
+	c.compileExpression(expression)
+	c.emit(code.OpIter)
+	c.storeSymbol(iterable)
+	c.compileConstant(object.NativeToInteger(0))
+	c.storeSymbol(index)
+	c.emit(code.OpGetBuiltin, getBuiltinIndex("len"))
+	c.loadSymbol(iterable)
+	c.emit(code.OpCall, 1)
+	c.storeSymbol(length)
+	return iterable, index, length
+}
+
+func (c *Compiler) defineHidden(name string) symbol.Symbol {
+	depth := len(c.currentScope().loops)
+	hidden := fmt.Sprintf("$%v%v", name, depth)
+	return c.checkSymbol(c.symbolTable.Define(hidden))
+}
+
+func getBuiltinIndex(name string) int {
+	for i, b := range object.Builtins {
+		if b.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Compiler) storeSymbol(sym symbol.Symbol) int {
+	return c.emit(c.getOpSet(sym), sym.Index)
+}
+
+func (c *Compiler) compileBreakStatement(statement *ast.BreakStatement) int {
+	l := c.currentLoop(statement, "break")
+	pos := c.emit(code.OpJump, 9999) // 9999 to replace
+	l.breaks = append(l.breaks, pos)
+	return pos
+}
+
+func (c *Compiler) currentLoop(node ast.Node, keyword string) *loop {
+	scope := c.currentScope()
+	if len(scope.loops) == 0 {
+		fail(node, "encountered %v outside of loop", keyword)
+	}
+	return scope.loops[len(scope.loops)-1]
+}
+
+func (c *Compiler) compileContinueStatement(
+	statement *ast.ContinueStatement,
+) int {
+	l := c.currentLoop(statement, "continue")
+	pos := c.emit(code.OpJump, 9999) // 9999 to replace
+	l.continues = append(l.continues, pos)
+	return pos
+}
@@ -0,0 +1,267 @@
+package compiler
+
+import (
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/token"
+)
+
+type instruction struct {
+	op       code.Opcode
+	operands []int
+	target   int
+	pos      token.Position
+	removed  bool
+}
+
+type pass func(instructions []*instruction) bool
+
+var loads = map[code.Opcode]code.Opcode{
+	code.OpSetGlobal: code.OpGetGlobal,
+	code.OpSetLocal:  code.OpGetLocal,
+	code.OpSetFree:   code.OpGetFree,
+}
+
+func (c *Compiler) optimize(s *scope) {
+	if c.level == None && len(s.jumps) == 0 {
+		return
+	}
+	instructions := decode(s.instructions, s.sourceMap, s.jumps)
+	passes := getPasses(c.level)
+	for changed := true; changed; {
+		changed = false
+		for _, p := range passes {
+			if p(instructions) {
+				changed = true
+			}
+			instructions = compact(instructions)
+		}
+	}
+	s.instructions, s.sourceMap = encode(instructions)
+	s.jumps = map[int]int{}
+}
+
+func getPasses(level Level) []pass {
+	if level == None {
+		return []pass{}
+	}
+	passes := []pass{
+		simplifyConditions,
+		threadJumps,
+		removeUnreachable,
+		removeJumpsToNext,
+	}
+	if level == Aggressive {
+		passes = append(passes, replaceJumpsToReturn, mergeStoreLoad)
+	}
+	return passes
+}
+
+func decode(
+	instructions code.Instructions,
+	sourceMap code.SourceMap,
+	jumps map[int]int,
+) []*instruction {
+	decoded := []*instruction{}
+	offsets := []int{}
+	indexes := map[int]int{}
+	for offset := 0; offset < len(instructions); {
+		op, operands, width := code.Unmake(instructions[offset:])
+		indexes[offset] = len(decoded)
+		decoded = append(decoded, &instruction{
+			op:       op,
+			operands: operands,
+			pos:      sourceMap.Lookup(offset),
+		})
+		offsets = append(offsets, offset)
+		offset += width
+	}
+	indexes[len(instructions)] = len(decoded)
+	for i, ins := range decoded {
+		if j, ok := code.JumpOperand[ins.op]; ok {
+			target, relocated := jumps[offsets[i]]
+			if !relocated {
+				target = ins.operands[j]
+			}
+			ins.target = indexes[target]
+		}
+	}
+	return decoded
+}
+
+func compact(instructions []*instruction) []*instruction {
+	indexes := make([]int, len(instructions)+1)
+	kept := []*instruction{}
+	for i, ins := range instructions {
+		indexes[i] = len(kept)
+		if !ins.removed {
+			kept = append(kept, ins)
+		}
+	}
+	indexes[len(instructions)] = len(kept)
+	for _, ins := range kept {
+		if _, ok := code.JumpOperand[ins.op]; ok {
+			ins.target = indexes[ins.target]
+		}
+	}
+	return kept
+}
+
+func encode(
+	instructions []*instruction,
+) (code.Instructions, code.SourceMap) {
+	offsets := layout(instructions)
+	encoded := code.Instructions{}
+	sourceMap := code.SourceMap{}
+	for i, ins := range instructions {
+		sourceMap = sourceMap.Add(offsets[i], ins.pos)
+		encoded = append(encoded, code.Make(ins.op, ins.operands...)...)
+	}
+	return encoded, sourceMap
+}
+
+func layout(instructions []*instruction) []int {
+	offsets := make([]int, len(instructions)+1)
+	for changed := true; changed; { // Until no jump needs a wider operand
+		changed = false
+		for i, ins := range instructions {
+			if j, ok := code.JumpOperand[ins.op]; ok {
+				ins.operands[j] = offsets[ins.target]
+			}
+			offset := offsets[i] + len(code.Make(ins.op, ins.operands...))
+			if offset != offsets[i+1] {
+				offsets[i+1] = offset
+				changed = true
+			}
+		}
+	}
+	return offsets
+}
+
+func getTargets(instructions []*instruction) map[int]bool {
+	targets := map[int]bool{}
+	for _, ins := range instructions {
+		if _, ok := code.JumpOperand[ins.op]; ok {
+			targets[ins.target] = true
+		}
+	}
+	return targets
+}
+
+func simplifyConditions(instructions []*instruction) bool {
+	targets := getTargets(instructions)
+	changed := false
+	for i := 0; i+1 < len(instructions); i++ {
+		condition, jump := instructions[i], instructions[i+1]
+		if jump.op != code.OpJumpIf || targets[i+1] {
+			continue
+		}
+		switch condition.op {
+		case code.OpTrue:
+			condition.removed, jump.removed = true, true
+		case code.OpFalse, code.OpNull:
+			condition.removed, jump.op = true, code.OpJump
+		default:
+			continue
+		}
+		changed = true
+	}
+	return changed
+}
+
+func threadJumps(instructions []*instruction) bool {
+	changed := false
+	for _, ins := range instructions {
+		if _, ok := code.JumpOperand[ins.op]; !ok {
+			continue
+		}
+		target := followJumps(instructions, ins.target)
+		if target != ins.target {
+			ins.target = target
+			changed = true
+		}
+	}
+	return changed
+}
+
+func followJumps(instructions []*instruction, target int) int {
+	visited := map[int]bool{}
+	for target < len(instructions) && instructions[target].op == code.OpJump {
+		if visited[target] {
+			break
+		}
+		visited[target] = true
+		target = instructions[target].target
+	}
+	return target
+}
+
+func removeUnreachable(instructions []*instruction) bool {
+	targets := getTargets(instructions)
+	changed, reachable := false, true
+	for i, ins := range instructions {
+		if targets[i] {
+			reachable = true
+		}
+		if !reachable {
+			ins.removed = true
+			changed = true
+			continue
+		}
+		reachable = !isTerminal(ins.op)
+	}
+	return changed
+}
+
+func isTerminal(op code.Opcode) bool {
+	switch op {
+	case code.OpJump, code.OpReturnValue, code.OpReturn:
+		return true
+	}
+	return false
+}
+
+func removeJumpsToNext(instructions []*instruction) bool {
+	changed := false
+	for i, ins := range instructions {
+		if ins.op == code.OpJump && ins.target == i+1 {
+			ins.removed = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+func replaceJumpsToReturn(instructions []*instruction) bool {
+	changed := false
+	for _, ins := range instructions {
+		if ins.op != code.OpJump || ins.target == len(instructions) {
+			continue
+		}
+		target := instructions[ins.target]
+		if target.op == code.OpReturnValue || target.op == code.OpReturn {
+			ins.op, ins.operands = target.op, []int{}
+			changed = true
+		}
+	}
+	return changed
+}
+
+func mergeStoreLoad(instructions []*instruction) bool {
+	targets := getTargets(instructions)
+	changed := false
+	for i := 0; i+1 < len(instructions); i++ {
+		store, load := instructions[i], instructions[i+1]
+		op, ok := loads[store.op]
+		if !ok || load.op != op || targets[i+1] {
+			continue
+		}
+		if store.operands[0] != load.operands[0] {
+			continue
+		}
+		store.op, load.op = code.OpDup, store.op
+		store.operands, load.operands = []int{}, store.operands
+		store.pos, load.pos = load.pos, store.pos
+		changed = true
+	}
+	return changed
+}
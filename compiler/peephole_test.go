@@ -0,0 +1,277 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/object"
+)
+
+func TestOptimize(t *testing.T) {
+	setup := []struct {
+		input             string
+		level             Level
+		expectedPieces    []code.Instructions
+		expectedConstants []object.Object
+	}{
+		{
+			`if (true) { 1 } else { 2 };`,
+			Default,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 2},
+			},
+		},
+		{
+			`if (1 > 2) { 1 };`,
+			Default,
+			[]code.Instructions{
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+			},
+		},
+		{
+			`while (true) { break; }`,
+			Default,
+			[]code.Instructions{},
+			[]object.Object{},
+		},
+		{
+			`while (true) { 1; }`,
+			Default,
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpJump, 0),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+			},
+		},
+		{
+			`fn() { return 1; 2; };`,
+			Default,
+			[]code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 2},
+				&object.CompiledFunction{
+					Instructions: code.Concatenate(
+						[]code.Instructions{
+							code.Make(code.OpConstant, 0),
+							code.Make(code.OpReturnValue),
+						},
+					),
+				},
+			},
+		},
+		{
+			`let x = true; if (x) { if (x) { 1 } else { 2 } } else { 3 };`,
+			None,
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpJumpIf, 28),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpJumpIf, 22),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpJump, 25),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpJump, 31),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 2},
+				&object.Integer{Value: 3},
+			},
+		},
+		{
+			`let x = true; if (x) { if (x) { 1 } else { 2 } } else { 3 };`,
+			Default,
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpJumpIf, 28),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpJumpIf, 22),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpJump, 31),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpJump, 31),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 2},
+				&object.Integer{Value: 3},
+			},
+		},
+		{
+			`let x = true; if (x) { if (x) { 1 } else { 2 } } else { 3 };`,
+			Aggressive,
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpDup),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpJumpIf, 26),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpJumpIf, 20),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpJump, 29),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpJump, 29),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 2},
+				&object.Integer{Value: 3},
+			},
+		},
+		{
+			`fn(x) { if (x) { 1 } else { 2 } };`,
+			Default,
+			[]code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 2},
+				&object.CompiledFunction{
+					Instructions: code.Concatenate(
+						[]code.Instructions{
+							code.Make(code.OpGetLocal, 0),
+							code.Make(code.OpJumpIf, 12),
+							code.Make(code.OpConstant, 0),
+							code.Make(code.OpJump, 15),
+							code.Make(code.OpConstant, 1),
+							code.Make(code.OpReturnValue),
+						},
+					),
+					NumLocals:     1,
+					NumParameters: 1,
+				},
+			},
+		},
+		{
+			`fn(x) { if (x) { 1 } else { 2 } };`,
+			Aggressive,
+			[]code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 2},
+				&object.CompiledFunction{
+					Instructions: code.Concatenate(
+						[]code.Instructions{
+							code.Make(code.OpGetLocal, 0),
+							code.Make(code.OpJumpIf, 10),
+							code.Make(code.OpConstant, 0),
+							code.Make(code.OpReturnValue),
+							code.Make(code.OpConstant, 1),
+							code.Make(code.OpReturnValue),
+						},
+					),
+					NumLocals:     1,
+					NumParameters: 1,
+				},
+			},
+		},
+		{
+			`fn() { let x = 1; x = x + 1; x };`,
+			Aggressive,
+			[]code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+			[]object.Object{
+				&object.Integer{Value: 1},
+				&object.CompiledFunction{
+					Instructions: code.Concatenate(
+						[]code.Instructions{
+							code.Make(code.OpConstant, 0),
+							code.Make(code.OpDup),
+							code.Make(code.OpSetLocal, 0),
+							code.Make(code.OpConstant, 0),
+							code.Make(code.OpAdd),
+							code.Make(code.OpDup),
+							code.Make(code.OpSetLocal, 0),
+							code.Make(code.OpPop),
+							code.Make(code.OpGetLocal, 0),
+							code.Make(code.OpReturnValue),
+						},
+					),
+					NumLocals: 1,
+				},
+			},
+		},
+	}
+
+	for _, s := range setup {
+		actual := compile(t, s.input, s.level)
+		expected := combine(s.expectedPieces, s.expectedConstants)
+		testBytecode(t, actual, expected)
+	}
+}
+
+func TestOptimizeSourceMap(t *testing.T) {
+	input := `if (false) { 1 }; len([]);`
+	for _, level := range []Level{None, Default, Aggressive} {
+		bytecode := compile(t, input, level)
+		offset := findOpcode(bytecode.Instructions, code.OpCall)
+		pos := bytecode.SourceMap.Lookup(offset)
+		if pos.Line != 1 || pos.Column != 19 {
+			t.Fatalf(
+				"position mismatch at level %v. got=%v, expected=1:19",
+				level,
+				pos,
+			)
+		}
+	}
+}
+
+func TestOptimizeSourceMapStore(t *testing.T) {
+	input := "let x = 1;\nx;"
+	for _, level := range []Level{None, Default, Aggressive} {
+		bytecode := compile(t, input, level)
+		offset := findOpcode(bytecode.Instructions, code.OpSetGlobal)
+		pos := bytecode.SourceMap.Lookup(offset)
+		if pos.Line != 1 || pos.Column != 1 {
+			t.Fatalf(
+				"position mismatch at level %v. got=%v, expected=1:1",
+				level,
+				pos,
+			)
+		}
+	}
+}
+
+func findOpcode(instructions code.Instructions, op code.Opcode) int {
+	for i := 0; i < len(instructions); {
+		actual, _, width := code.Unmake(instructions[i:])
+		if actual == op {
+			return i
+		}
+		i += width
+	}
+	return -1
+}
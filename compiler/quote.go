@@ -0,0 +1,49 @@
+package compiler
+
+import (
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/evaluator"
+	"github.com/vincentlabelle/monkey/object"
+)
+
+func (c *Compiler) compileQuote(expression *ast.CallExpression) {
+	if len(expression.Arguments) != 1 {
+		message := "quote expects exactly one argument; got=%v"
+		fail(expression, message, len(expression.Arguments))
+	}
+	quoted := expression.Arguments[0]
+	calls := findUnquoteCalls(quoted)
+	if len(calls) == 0 {
+		c.compileConstant(&object.Quote{Node: quoted})
+		return
+	}
+	for _, call := range calls {
+		c.compileUnquoteCall(call)
+	}
+	pos := c.addConstant(&object.Quote{Node: quoted})
+	c.emit(code.OpQuote, pos, len(calls))
+}
+
+func findUnquoteCalls(quoted ast.Node) []*ast.CallExpression {
+	calls := []*ast.CallExpression{}
+	ast.Rewrite(quoted, func(node ast.Node) ast.Node {
+		if evaluator.IsUnquoteCall(node) {
+			calls = append(calls, node.(*ast.CallExpression))
+		}
+		return node
+	})
+	return calls
+}
+
+func (c *Compiler) compileUnquoteCall(call *ast.CallExpression) {
+	if len(call.Arguments) != 1 {
+		message := "unquote expects exactly one argument; got=%v"
+		fail(call, message, len(call.Arguments))
+	}
+	unquoted := call.Arguments[0]
+	if node, ok := evaluator.FindUnquoteCall(unquoted); ok {
+		fail(node, "nested unquote isn't supported by compiler")
+	}
+	c.compileExpression(unquoted)
+}
@@ -0,0 +1,23 @@
+package compiler
+
+import "github.com/vincentlabelle/monkey/code"
+
+type scope struct {
+	instructions code.Instructions
+	sourceMap    code.SourceMap
+	jumps        map[int]int
+	loops        []*loop
+}
+
+type loop struct {
+	breaks    []int
+	continues []int
+}
+
+func newScope() *scope {
+	return &scope{
+		instructions: code.Instructions{},
+		sourceMap:    code.SourceMap{},
+		jumps:        map[int]int{},
+	}
+}
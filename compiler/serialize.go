@@ -0,0 +1,354 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"slices"
+
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/lexer"
+	"github.com/vincentlabelle/monkey/object"
+	"github.com/vincentlabelle/monkey/parser"
+	"github.com/vincentlabelle/monkey/token"
+)
+
+const (
+	Magic   = "\x7fMKC"
+	Version = 2
+)
+
+const (
+	headerSize   = len(Magic) + 2
+	checksumSize = 4
+)
+
+const (
+	tagInteger byte = iota
+	tagFloat
+	tagString
+	tagFunction
+	tagQuote
+)
+
+func (b *Bytecode) MarshalBinary() ([]byte, error) {
+	e := &encoder{data: []byte(Magic), files: map[string]int{}}
+	e.data = binary.BigEndian.AppendUint16(e.data, Version)
+	e.writeInstructions(b.Instructions)
+	e.writeSourceMap(b.SourceMap)
+	e.writeConstants(b.Constants)
+	if e.err != nil {
+		return nil, e.err
+	}
+	checksum := crc32.ChecksumIEEE(e.data)
+	return binary.BigEndian.AppendUint32(e.data, checksum), nil
+}
+
+type encoder struct {
+	data  []byte
+	files map[string]int
+	err   error
+}
+
+func (e *encoder) writeUvarint(value int) {
+	e.data = binary.AppendUvarint(e.data, uint64(value))
+}
+
+func (e *encoder) writeVarint(value int) {
+	e.data = binary.AppendVarint(e.data, int64(value))
+}
+
+func (e *encoder) writeString(value string) {
+	e.writeUvarint(len(value))
+	e.data = append(e.data, value...)
+}
+
+func (e *encoder) writeInstructions(instructions code.Instructions) {
+	e.writeUvarint(len(instructions))
+	e.data = append(e.data, instructions...)
+}
+
+func (e *encoder) writeSourceMap(sourceMap code.SourceMap) {
+	e.writeUvarint(len(sourceMap))
+	previous := code.Mapping{}
+	for _, mapping := range sourceMap {
+		e.writeUvarint(mapping.Offset - previous.Offset)
+		e.writeFile(mapping.Pos.File)
+		e.writeVarint(mapping.Pos.Line - previous.Pos.Line)
+		e.writeUvarint(mapping.Pos.Column)
+		e.writeVarint(mapping.Pos.Offset - previous.Pos.Offset)
+		previous = mapping
+	}
+}
+
+func (e *encoder) writeFile(file string) {
+	if index, ok := e.files[file]; ok {
+		e.writeUvarint(index)
+		return
+	}
+	e.files[file] = len(e.files)
+	e.writeUvarint(e.files[file])
+	e.writeString(file)
+}
+
+func (e *encoder) writeConstants(constants []object.Object) {
+	e.writeUvarint(len(constants))
+	for _, obj := range constants {
+		e.writeConstant(obj)
+	}
+}
+
+func (e *encoder) writeConstant(obj object.Object) {
+	switch o := obj.(type) {
+	case *object.Integer:
+		e.data = append(e.data, tagInteger)
+		e.writeVarint(o.Value)
+	case *object.Float:
+		e.data = append(e.data, tagFloat)
+		e.data = binary.BigEndian.AppendUint64(
+			e.data,
+			math.Float64bits(o.Value),
+		)
+	case *object.String:
+		e.data = append(e.data, tagString)
+		e.writeString(o.Value)
+	case *object.CompiledFunction:
+		e.data = append(e.data, tagFunction)
+		e.writeFunction(o)
+	case *object.Quote:
+		e.data = append(e.data, tagQuote)
+		e.writeString(ast.Format(o.Node))
+	default:
+		message := "cannot save bytecode; unexpected constant %v"
+		e.err = fmt.Errorf(message, object.TypeOf(obj))
+	}
+}
+
+func (e *encoder) writeFunction(fn *object.CompiledFunction) {
+	e.writeString(fn.Name)
+	e.writeUvarint(fn.NumLocals)
+	e.writeUvarint(fn.NumParameters)
+	e.writeInstructions(fn.Instructions)
+	e.writeSourceMap(fn.SourceMap)
+}
+
+func (b *Bytecode) UnmarshalBinary(data []byte) error {
+	if err := validateHeader(data); err != nil {
+		return err
+	}
+	if err := validateChecksum(data); err != nil {
+		return err
+	}
+	d := &decoder{data: data[headerSize : len(data)-checksumSize]}
+	instructions := d.readInstructions()
+	sourceMap := d.readSourceMap()
+	constants := d.readConstants()
+	if d.err == nil && len(d.data) > 0 {
+		d.fail("unexpected data after constants")
+	}
+	if d.err != nil {
+		return d.err
+	}
+	b.Instructions = instructions
+	b.SourceMap = sourceMap
+	b.Constants = constants
+	return nil
+}
+
+func validateHeader(data []byte) error {
+	if len(data) < headerSize+checksumSize {
+		return errors.New("cannot load bytecode; missing magic header")
+	}
+	if string(data[:len(Magic)]) != Magic {
+		return errors.New("cannot load bytecode; missing magic header")
+	}
+	version := binary.BigEndian.Uint16(data[len(Magic):])
+	if version != Version {
+		message := "cannot load bytecode; unsupported version %v, expected %v"
+		return fmt.Errorf(message, version, Version)
+	}
+	return nil
+}
+
+func validateChecksum(data []byte) error {
+	payload := data[:len(data)-checksumSize]
+	expected := binary.BigEndian.Uint32(data[len(payload):])
+	if crc32.ChecksumIEEE(payload) != expected {
+		return errors.New("cannot load bytecode; checksum mismatch")
+	}
+	return nil
+}
+
+type decoder struct {
+	data  []byte
+	files []string
+	err   error
+}
+
+func (d *decoder) fail(format string, a ...any) {
+	if d.err == nil {
+		message := "cannot load bytecode; " + format
+		d.err = fmt.Errorf(message, a...)
+	}
+}
+
+func (d *decoder) readByte() byte {
+	if d.err != nil || len(d.data) == 0 {
+		d.fail("unexpected end of data")
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+func (d *decoder) readBytes(n int) []byte {
+	if d.err != nil || n > len(d.data) {
+		d.fail("unexpected end of data")
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *decoder) readUvarint() int {
+	if d.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(d.data)
+	if n == 0 {
+		d.fail("unexpected end of data")
+		return 0
+	}
+	if n < 0 || value > math.MaxInt32 {
+		d.fail("invalid unsigned integer")
+		return 0
+	}
+	d.data = d.data[n:]
+	return int(value)
+}
+
+func (d *decoder) readVarint() int {
+	if d.err != nil {
+		return 0
+	}
+	value, n := binary.Varint(d.data)
+	if n == 0 {
+		d.fail("unexpected end of data")
+		return 0
+	}
+	if n < 0 {
+		d.fail("invalid integer")
+		return 0
+	}
+	d.data = d.data[n:]
+	return int(value)
+}
+
+func (d *decoder) readFloat() float64 {
+	b := d.readBytes(8)
+	if b == nil {
+		return 0
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b))
+}
+
+func (d *decoder) readString() string {
+	return string(d.readBytes(d.readUvarint()))
+}
+
+func (d *decoder) readInstructions() code.Instructions {
+	return slices.Clone(d.readBytes(d.readUvarint()))
+}
+
+func (d *decoder) readSourceMap() code.SourceMap {
+	sourceMap := code.SourceMap{}
+	count := d.readUvarint()
+	previous := code.Mapping{}
+	for i := 0; i < count && d.err == nil; i++ {
+		mapping := code.Mapping{
+			Offset: previous.Offset + d.readUvarint(),
+			Pos: token.Position{
+				File:   d.readFile(),
+				Line:   previous.Pos.Line + d.readVarint(),
+				Column: d.readUvarint(),
+				Offset: previous.Pos.Offset + d.readVarint(),
+			},
+		}
+		sourceMap = append(sourceMap, mapping)
+		previous = mapping
+	}
+	return sourceMap
+}
+
+func (d *decoder) readFile() string {
+	index := d.readUvarint()
+	if index < len(d.files) {
+		return d.files[index]
+	}
+	if index > len(d.files) {
+		d.fail("undefined file %v", index)
+		return ""
+	}
+	file := d.readString()
+	if d.err == nil {
+		d.files = append(d.files, file)
+	}
+	return file
+}
+
+func (d *decoder) readConstants() []object.Object {
+	constants := []object.Object{}
+	count := d.readUvarint()
+	for i := 0; i < count && d.err == nil; i++ {
+		constants = append(constants, d.readConstant())
+	}
+	return constants
+}
+
+func (d *decoder) readConstant() object.Object {
+	switch tag := d.readByte(); tag {
+	case tagInteger:
+		return object.NativeToInteger(d.readVarint())
+	case tagFloat:
+		return object.NativeToFloat(d.readFloat())
+	case tagString:
+		return object.NativeToString(d.readString())
+	case tagFunction:
+		return d.readFunction()
+	case tagQuote:
+		return d.readQuote()
+	default:
+		d.fail("unexpected constant tag %v", tag)
+		return nil
+	}
+}
+
+func (d *decoder) readFunction() *object.CompiledFunction {
+	return &object.CompiledFunction{
+		Name:          d.readString(),
+		NumLocals:     d.readUvarint(),
+		NumParameters: d.readUvarint(),
+		Instructions:  d.readInstructions(),
+		SourceMap:     d.readSourceMap(),
+	}
+}
+
+func (d *decoder) readQuote() *object.Quote {
+	source := d.readString()
+	program, errs := parser.New(lexer.New(source)).ParseProgram()
+	if len(errs) > 0 || len(program.Statements) != 1 {
+		d.fail("invalid quote %q", source)
+		return nil
+	}
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		d.fail("invalid quote %q", source)
+		return nil
+	}
+	return &object.Quote{Node: statement.Expression}
+}
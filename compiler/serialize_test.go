@@ -0,0 +1,190 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"reflect"
+	"testing"
+
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/object"
+	"github.com/vincentlabelle/monkey/token"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	input := `
+		let add = fn(a, b) { let f = fn(x) { x * a }; f(b) + 0.5 };
+		let s = "monkey";
+		let q = quote(1 + -2 * s);
+		add(-3, 4);
+	`
+	expected := compile(t, input, Default)
+	data, err := expected.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	actual := &Bytecode{}
+	if err := actual.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+
+	testInstructions(t, actual.Instructions, expected.Instructions)
+	if !reflect.DeepEqual(actual.SourceMap, expected.SourceMap) {
+		t.Fatalf(
+			"source map mismatch. got=%v, expected=%v",
+			actual.SourceMap,
+			expected.SourceMap,
+		)
+	}
+	for i, obj := range actual.Constants {
+		if obj.Inspect() != expected.Constants[i].Inspect() {
+			t.Fatalf(
+				"constant mismatch. got=%v, expected=%v",
+				obj.Inspect(),
+				expected.Constants[i].Inspect(),
+			)
+		}
+	}
+	again, err := actual.MarshalBinary()
+	if err != nil || !bytes.Equal(again, data) {
+		t.Fatalf("round trip mismatch. got=%v, expected=%v", again, data)
+	}
+}
+
+func TestMarshalBinaryFunction(t *testing.T) {
+	expected := compile(t, `let f = fn(a) { let b = a; len(b) };`, Default)
+	data, _ := expected.MarshalBinary()
+	actual := &Bytecode{}
+	if err := actual.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	testConstants(t, actual.Constants, expected.Constants)
+	a := actual.Constants[0].(*object.CompiledFunction)
+	e := expected.Constants[0].(*object.CompiledFunction)
+	if a.Name != "f" || !reflect.DeepEqual(a.SourceMap, e.SourceMap) {
+		t.Fatalf(
+			"function mismatch. got=%v %v, expected=f %v",
+			a.Name,
+			a.SourceMap,
+			e.SourceMap,
+		)
+	}
+}
+
+func TestMarshalBinarySourceMap(t *testing.T) {
+	main := token.Position{File: "/main.mk", Line: 3, Column: 1, Offset: 20}
+	lib := token.Position{File: "/lib.mk", Line: 1, Column: 5, Offset: 4}
+	expected := &Bytecode{
+		Instructions: code.Instructions{},
+		Constants: []object.Object{
+			&object.CompiledFunction{
+				Instructions: code.Instructions{},
+				SourceMap: code.SourceMap{
+					{Offset: 0, Pos: lib},
+					{Offset: 2, Pos: main},
+				},
+			},
+		},
+		SourceMap: code.SourceMap{
+			{Offset: 0, Pos: main},
+			{Offset: 4, Pos: lib},
+			{Offset: 9, Pos: token.Position{}},
+		},
+	}
+	data, _ := expected.MarshalBinary()
+	actual := &Bytecode{}
+	if err := actual.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	if !reflect.DeepEqual(actual.SourceMap, expected.SourceMap) {
+		t.Fatalf(
+			"source map mismatch. got=%v, expected=%v",
+			actual.SourceMap,
+			expected.SourceMap,
+		)
+	}
+	a := actual.Constants[0].(*object.CompiledFunction)
+	e := expected.Constants[0].(*object.CompiledFunction)
+	if !reflect.DeepEqual(a.SourceMap, e.SourceMap) {
+		t.Fatalf(
+			"source map mismatch. got=%v, expected=%v",
+			a.SourceMap,
+			e.SourceMap,
+		)
+	}
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	valid, _ := compile(t, `fn() { 1.5 };`, Default).MarshalBinary()
+	setup := []struct {
+		data     []byte
+		expected string
+	}{
+		{
+			[]byte{},
+			"cannot load bytecode; missing magic header",
+		},
+		{
+			[]byte("let x = 1; puts(x);"),
+			"cannot load bytecode; missing magic header",
+		},
+		{
+			withVersion(valid, Version+1),
+			"cannot load bytecode; unsupported version 3, expected 2",
+		},
+		{
+			withByte(valid, len(valid)/2),
+			"cannot load bytecode; checksum mismatch",
+		},
+		{
+			withChecksum(valid[:len(valid)-8]),
+			"cannot load bytecode; unexpected end of data",
+		},
+		{
+			withChecksum(append(bytes.Clone(valid[:len(valid)-4]), 0)),
+			"cannot load bytecode; unexpected data after constants",
+		},
+		{
+			withChecksum(append([]byte(Magic), 0, Version, 0, 0, 1, 9)),
+			"cannot load bytecode; unexpected constant tag 9",
+		},
+		{
+			withChecksum(append([]byte(Magic), 0, Version, 0, 1, 0, 5)),
+			"cannot load bytecode; undefined file 5",
+		},
+	}
+
+	for _, s := range setup {
+		err := (&Bytecode{}).UnmarshalBinary(s.data)
+		if err == nil || err.Error() != s.expected {
+			t.Fatalf("error mismatch. got=%v, expected=%q", err, s.expected)
+		}
+	}
+}
+
+func TestMarshalBinaryErrors(t *testing.T) {
+	bytecode := &Bytecode{Constants: []object.Object{&object.Array{}}}
+	_, err := bytecode.MarshalBinary()
+	expected := "cannot save bytecode; unexpected constant Array"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("error mismatch. got=%v, expected=%q", err, expected)
+	}
+}
+
+func withVersion(data []byte, version int) []byte {
+	modified := bytes.Clone(data)
+	binary.BigEndian.PutUint16(modified[len(Magic):], uint16(version))
+	return modified
+}
+
+func withByte(data []byte, i int) []byte {
+	modified := bytes.Clone(data)
+	modified[i] ^= 0xff
+	return modified
+}
+
+func withChecksum(payload []byte) []byte {
+	checksum := crc32.ChecksumIEEE(payload)
+	return binary.BigEndian.AppendUint32(bytes.Clone(payload), checksum)
+}
@@ -0,0 +1,193 @@
+package disasm
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/compiler"
+	"github.com/vincentlabelle/monkey/object"
+	"github.com/vincentlabelle/monkey/token"
+)
+
+const commentColumn = 32
+
+type Reader func(file string) ([]byte, error)
+
+type disassembler struct {
+	builder   strings.Builder
+	constants []object.Object
+	read      Reader
+	sources   map[string][]string
+}
+
+func Disassemble(bytecode *compiler.Bytecode, read Reader) string {
+	d := &disassembler{
+		constants: bytecode.Constants,
+		read:      read,
+		sources:   map[string][]string{},
+	}
+	d.write("<main>:\n")
+	d.writeInstructions(bytecode.Instructions, bytecode.SourceMap)
+	for i, obj := range bytecode.Constants {
+		if fn, ok := obj.(*object.CompiledFunction); ok {
+			d.writeFunction(i, fn)
+		}
+	}
+	return d.builder.String()
+}
+
+func (d *disassembler) write(s string) {
+	d.builder.WriteString(s)
+}
+
+func (d *disassembler) writeFunction(index int, fn *object.CompiledFunction) {
+	d.write(fmt.Sprintf(
+		"\n%v (constant %v, parameters %v, locals %v):\n",
+		getName(fn),
+		index,
+		fn.NumParameters,
+		fn.NumLocals,
+	))
+	d.writeInstructions(fn.Instructions, fn.SourceMap)
+}
+
+func getName(fn *object.CompiledFunction) string {
+	if fn.Name == "" {
+		return "fn <anonymous>"
+	}
+	return "fn " + fn.Name
+}
+
+func (d *disassembler) writeInstructions(
+	instructions code.Instructions,
+	sourceMap code.SourceMap,
+) {
+	labels := getLabels(instructions)
+	last := token.Position{}
+	for offset := 0; offset < len(instructions); {
+		op, operands, width := code.Unmake(instructions[offset:])
+		d.writeLabel(labels, offset)
+		pos := sourceMap.Lookup(offset)
+		if pos.IsValid() && (pos.File != last.File || pos.Line != last.Line) {
+			d.writeSource(pos)
+			last = pos
+		}
+		wide := code.Opcode(instructions[offset]) == code.OpWide
+		d.writeInstruction(offset, op, operands, wide, labels)
+		offset += width
+	}
+	d.writeLabel(labels, len(instructions))
+}
+
+func getLabels(instructions code.Instructions) map[int]string {
+	targets := []int{}
+	for offset := 0; offset < len(instructions); {
+		op, operands, width := code.Unmake(instructions[offset:])
+		if i, ok := code.JumpOperand[op]; ok {
+			targets = append(targets, operands[i])
+		}
+		offset += width
+	}
+	slices.Sort(targets)
+	labels := map[int]string{}
+	for _, target := range slices.Compact(targets) {
+		labels[target] = fmt.Sprintf("L%v", len(labels)+1)
+	}
+	return labels
+}
+
+func (d *disassembler) writeLabel(labels map[int]string, offset int) {
+	if label, ok := labels[offset]; ok {
+		d.write(label + ":\n")
+	}
+}
+
+func (d *disassembler) writeSource(pos token.Position) {
+	text := fmt.Sprintf("    // %v", pos.Line)
+	if pos.File != "" {
+		text = fmt.Sprintf("    // %v:%v", filepath.Base(pos.File), pos.Line)
+	}
+	if line, ok := d.getLine(pos); ok {
+		text += ": " + line
+	}
+	d.write(text + "\n")
+}
+
+func (d *disassembler) getLine(pos token.Position) (string, bool) {
+	lines, ok := d.sources[pos.File]
+	if !ok {
+		lines = d.readLines(pos.File)
+		d.sources[pos.File] = lines
+	}
+	if pos.Line > len(lines) {
+		return "", false
+	}
+	return strings.TrimSpace(lines[pos.Line-1]), true
+}
+
+func (d *disassembler) readLines(file string) []string {
+	if d.read == nil {
+		return nil
+	}
+	data, err := d.read(file)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(data), "\n")
+}
+
+func (d *disassembler) writeInstruction(
+	offset int,
+	op code.Opcode,
+	operands []int,
+	wide bool,
+	labels map[int]string,
+) {
+	text := fmt.Sprintf("    %04d %v", offset, op)
+	if wide {
+		text = fmt.Sprintf("    %04d %v %v", offset, code.OpWide, op)
+	}
+	for i, operand := range operands {
+		if j, ok := code.JumpOperand[op]; ok && i == j {
+			text += " " + labels[operand]
+		} else {
+			text += fmt.Sprintf(" %v", operand)
+		}
+	}
+	if comment := d.describe(op, operands); comment != "" {
+		width := max(commentColumn, len(text)+1)
+		text = fmt.Sprintf("%-*s; %v", width, text, comment)
+	}
+	d.write(text + "\n")
+}
+
+func (d *disassembler) describe(op code.Opcode, operands []int) string {
+	switch op {
+	case code.OpConstant, code.OpClosure, code.OpQuote:
+		return d.describeConstant(operands[0])
+	case code.OpGetBuiltin:
+		if operands[0] < len(object.Builtins) {
+			return object.Builtins[operands[0]].Name
+		}
+		return "undefined built-in"
+	}
+	return ""
+}
+
+func (d *disassembler) describeConstant(index int) string {
+	if index >= len(d.constants) {
+		return "undefined constant"
+	}
+	switch obj := d.constants[index].(type) {
+	case *object.String:
+		return strconv.Quote(obj.Value)
+	case *object.CompiledFunction:
+		return getName(obj)
+	default:
+		return obj.Inspect()
+	}
+}
@@ -0,0 +1,179 @@
+package disasm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/compiler"
+	"github.com/vincentlabelle/monkey/lexer"
+	"github.com/vincentlabelle/monkey/object"
+	"github.com/vincentlabelle/monkey/parser"
+)
+
+func TestDisassemble(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			`let x = true;
+			while (x) {
+				x = false;
+			}`,
+			[]string{
+				"<main>:",
+				"    // 1: let x = true;",
+				"    0000 OpTrue",
+				"    0001 OpSetGlobal 0",
+				"L1:",
+				"    // 2: while (x) {",
+				"    0004 OpGetGlobal 0",
+				"    0007 OpJumpIf L2",
+				"    // 3: x = false;",
+				"    0010 OpFalse",
+				"    0011 OpSetGlobal 0",
+				"    0014 OpGetGlobal 0",
+				"    0017 OpPop",
+				"    // 2: while (x) {",
+				"    0018 OpJump L1",
+				"L2:",
+			},
+		},
+		{
+			`let add = fn(a, b) {
+				let f = fn() { a };
+				if (a > b) { f() } else { len("xy") }
+			};
+			add(1, 2.5);
+			quote(x + 1);
+			fn() {};`,
+			[]string{
+				"<main>:",
+				"    // 1: let add = fn(a, b) {",
+				"    0000 OpClosure 2 0          ; fn add",
+				"    0004 OpSetGlobal 0",
+				"    // 5: add(1, 2.5);",
+				"    0007 OpGetGlobal 0",
+				"    0010 OpConstant 3           ; 1",
+				"    0013 OpConstant 4           ; 2.5",
+				"    0016 OpCall 2",
+				"    0018 OpPop",
+				"    // 6: quote(x + 1);",
+				"    0019 OpConstant 5           ; quote(x + 1)",
+				"    0022 OpPop",
+				"    // 7: fn() {};",
+				"    0023 OpClosure 6 0          ; fn <anonymous>",
+				"    0027 OpPop",
+				"",
+				"fn f (constant 0, parameters 0, locals 0):",
+				"    // 2: let f = fn() { a };",
+				"    0000 OpGetFree 0",
+				"    0002 OpReturnValue",
+				"",
+				"fn add (constant 2, parameters 2, locals 3):",
+				"    // 2: let f = fn() { a };",
+				"    0000 OpCaptureLocal 0",
+				"    0003 OpClosure 0 1          ; fn f",
+				"    0007 OpSetLocal 2",
+				"    // 3: if (a > b) { f() } else { len(\"xy\") }",
+				"    0010 OpGetLocal 0",
+				"    0013 OpGetLocal 1",
+				"    0016 OpGreaterThan",
+				"    0017 OpJumpIf L1",
+				"    0020 OpGetLocal 2",
+				"    0023 OpCall 0",
+				"    0025 OpJump L2",
+				"L1:",
+				"    0028 OpGetBuiltin 0         ; len",
+				"    0030 OpConstant 1           ; \"xy\"",
+				"    0033 OpCall 1",
+				"L2:",
+				"    0035 OpReturnValue",
+				"",
+				"fn <anonymous> (constant 6, parameters 0, locals 0):",
+				"    // 7: fn() {};",
+				"    0000 OpReturn",
+			},
+		},
+	}
+
+	for _, s := range setup {
+		read := func(string) ([]byte, error) { return []byte(s.input), nil }
+		actual := Disassemble(compile(t, s.input), read)
+		expected := strings.Join(s.expected, "\n") + "\n"
+		if actual != expected {
+			t.Fatalf(
+				"disassembly mismatch. got=\n%v\nexpected=\n%v",
+				actual,
+				expected,
+			)
+		}
+	}
+}
+
+func TestDisassembleWithoutSource(t *testing.T) {
+	actual := Disassemble(compile(t, "1;\n\n2;"), nil)
+	expected := strings.Join([]string{
+		"<main>:",
+		"    // 1",
+		"    0000 OpConstant 0           ; 1",
+		"    0003 OpPop",
+		"    // 3",
+		"    0004 OpConstant 1           ; 2",
+		"    0007 OpPop",
+	}, "\n") + "\n"
+	if actual != expected {
+		t.Fatalf(
+			"disassembly mismatch. got=\n%v\nexpected=\n%v",
+			actual,
+			expected,
+		)
+	}
+}
+
+func TestDisassembleWide(t *testing.T) {
+	instructions := code.Concatenate([]code.Instructions{
+		code.Make(code.OpGetGlobal, 65535),
+		code.Make(code.OpJump, 70000),
+		code.Make(code.OpGetGlobal, 65536),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpConstant, 65536),
+	})
+	constants := make([]object.Object, 65537)
+	for i := range constants {
+		constants[i] = &object.Integer{Value: i}
+	}
+	bytecode := &compiler.Bytecode{
+		Instructions: instructions,
+		Constants:    constants,
+	}
+	actual := Disassemble(bytecode, nil)
+	expected := strings.Join([]string{
+		"<main>:",
+		"    0000 OpGetGlobal 65535",
+		"    0003 OpWide OpJump L1",
+		"    0009 OpWide OpGetGlobal 65536",
+		"    0015 OpConstant 1           ; 1",
+		"    0018 OpWide OpConstant 65536 ; 65536",
+	}, "\n") + "\n"
+	if actual != expected {
+		t.Fatalf(
+			"disassembly mismatch. got=\n%v\nexpected=\n%v",
+			actual,
+			expected,
+		)
+	}
+}
+
+func compile(t *testing.T, input string) *compiler.Bytecode {
+	program, errors := parser.New(lexer.New(input)).ParseProgram()
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors. got=%v", errors)
+	}
+	bytecode, err := compiler.New().Compile(program)
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	return bytecode
+}
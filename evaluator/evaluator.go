@@ -1,13 +1,26 @@
 package evaluator
 
 import (
-	"log"
+	"maps"
 
 	"github.com/vincentlabelle/monkey/ast"
 	"github.com/vincentlabelle/monkey/object"
 )
 
-func Eval(program *ast.Program, env *object.Environment) object.Object {
+const MaxCallDepth = 1024
+
+func Eval(
+	program *ast.Program,
+	env *object.Environment,
+) (object.Object, error) {
+	obj := evalProgram(program, env)
+	if err, ok := obj.(*object.Error); ok {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var obj object.Object
 	for _, statement := range program.Statements {
 		switch s := statement.(type) {
@@ -15,19 +28,33 @@ func Eval(program *ast.Program, env *object.Environment) object.Object {
 			return evalExpression(s.Value, env)
 		case *ast.ExpressionStatement:
 			obj = evalExpression(s.Expression, env)
-			if rv, ok := obj.(*object.ReturnValue); ok {
-				return rv.Value
-			}
 		case *ast.LetStatement:
 			obj = evalLetStatement(s, env)
+		case *ast.WhileStatement:
+			obj = evalWhileStatement(s, env)
+		case *ast.ForStatement:
+			obj = evalForStatement(s, env)
 		default:
 			message := "cannot evaluate program; unexpected statement type"
-			log.Fatal(message)
+			obj = locate(object.NewError(message), s)
+		}
+		if rv, ok := obj.(*object.ReturnValue); ok {
+			return rv.Value
+		}
+		if object.IsError(obj) {
+			return obj
 		}
 	}
 	return obj
 }
 
+func locate(obj object.Object, node ast.Node) object.Object {
+	if err, ok := obj.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+	return obj
+}
+
 func evalExpression(
 	expression ast.Expression,
 	env *object.Environment,
@@ -36,6 +63,8 @@ func evalExpression(
 	switch e := expression.(type) {
 	case *ast.IntegerLiteral:
 		obj = object.NativeToInteger(e.Value)
+	case *ast.FloatLiteral:
+		obj = object.NativeToFloat(e.Value)
 	case *ast.BooleanLiteral:
 		obj = object.NativeToBoolean(e.Value)
 	case *ast.StringLiteral:
@@ -58,11 +87,19 @@ func evalExpression(
 		obj = evalIndexExpression(e, env)
 	case *ast.HashLiteral:
 		obj = evalHashLiteral(e, env)
+	case *ast.AssignExpression:
+		obj = evalAssignExpression(e, env)
+	case *ast.ImportExpression:
+		obj = evalImportExpression(e, env)
+	case *ast.MacroLiteral:
+		message := "cannot evaluate program; " +
+			"macro must be bound by a top-level let statement"
+		obj = object.NewError(message)
 	default:
 		message := "cannot evaluate program; unexpected expression type"
-		log.Fatal(message)
+		obj = object.NewError(message)
 	}
-	return obj
+	return locate(obj, expression)
 }
 
 func evalIdentifier(
@@ -71,8 +108,9 @@ func evalIdentifier(
 ) object.Object {
 	obj, ok := env.Get(expression.Value)
 	if !ok {
-		message := "cannot evaluate program; encountered undefined identifier"
-		log.Fatal(message)
+		message := "cannot evaluate program; " +
+			"encountered undefined identifier %v"
+		return object.NewError(message, expression.Value)
 	}
 	return obj
 }
@@ -82,6 +120,9 @@ func evalPrefixExpression(
 	env *object.Environment,
 ) object.Object {
 	right := evalExpression(expression.Right, env)
+	if object.IsError(right) {
+		return right
+	}
 	return EvalPrefix(expression.Operator, right)
 }
 
@@ -89,25 +130,57 @@ func evalInfixExpression(
 	expression *ast.InfixExpression,
 	env *object.Environment,
 ) object.Object {
+	if isLogicalOperator(expression.Operator) {
+		return evalLogicalExpression(expression, env)
+	}
 	left := evalExpression(expression.Left, env)
+	if object.IsError(left) {
+		return left
+	}
 	right := evalExpression(expression.Right, env)
+	if object.IsError(right) {
+		return right
+	}
 	return EvalInfix(left, expression.Operator, right)
 }
 
-func evalIfExpression(
-	expression *ast.IfExpression,
+func isLogicalOperator(operator string) bool {
+	return operator == "&&" || operator == "||"
+}
+
+func evalLogicalExpression(
+	expression *ast.InfixExpression,
 	env *object.Environment,
 ) object.Object {
-	condition := evalIfExpressionCondition(expression.Condition, env)
-	return evalIfExpressionBlock(condition, expression, env)
+	left := evalExpression(expression.Left, env)
+	if object.IsError(left) {
+		return left
+	}
+	truthy := EvalTruthy(left)
+	if truthy.Value == (expression.Operator == "||") {
+		return truthy
+	}
+	right := evalExpression(expression.Right, env)
+	if object.IsError(right) {
+		return right
+	}
+	return EvalTruthy(right)
 }
 
-func evalIfExpressionCondition(
-	expression ast.Expression,
+func evalIfExpression(
+	expression *ast.IfExpression,
 	env *object.Environment,
-) *object.Boolean {
-	obj := evalExpression(expression, env)
-	return EvalTruthy(obj)
+) object.Object {
+	obj := evalExpression(expression.Condition, env)
+	if object.IsError(obj) {
+		return obj
+	}
+	condition := EvalTruthy(obj)
+	obj = evalIfExpressionBlock(condition, expression, env)
+	if obj == nil {
+		return object.NULL
+	}
+	return obj
 }
 
 func evalIfExpressionBlock(
@@ -133,30 +206,108 @@ func evalBlockStatements(
 		switch s := statement.(type) {
 		case *ast.ReturnStatement:
 			obj = evalExpression(s.Value, env)
+			if object.IsError(obj) {
+				return obj
+			}
 			return &object.ReturnValue{Value: obj}
 		case *ast.ExpressionStatement:
 			obj = evalExpression(s.Expression, env)
-			if rv, ok := obj.(*object.ReturnValue); ok {
-				return rv
-			}
 		case *ast.LetStatement:
 			obj = evalLetStatement(s, env)
+		case *ast.WhileStatement:
+			obj = evalWhileStatement(s, env)
+		case *ast.ForStatement:
+			obj = evalForStatement(s, env)
+		case *ast.BreakStatement:
+			obj = object.BREAK
+		case *ast.ContinueStatement:
+			obj = object.CONTINUE
 		default:
 			message := "cannot evaluate program; unexpected statement type"
-			log.Fatal(message)
+			obj = locate(object.NewError(message), s)
+		}
+		if isInterruption(obj) {
+			return obj
 		}
 	}
 	return obj
 }
 
+func isInterruption(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.ReturnValue, *object.Break, *object.Continue, *object.Error:
+		return true
+	default:
+		return false
+	}
+}
+
+func evalWhileStatement(
+	statement *ast.WhileStatement,
+	env *object.Environment,
+) object.Object {
+	for {
+		obj := evalExpression(statement.Condition, env)
+		if object.IsError(obj) {
+			return obj
+		}
+		if !EvalTruthy(obj).Value {
+			return nil
+		}
+		obj = evalBlockStatements(statement.Body, env)
+		if isLoopExit(obj) {
+			return exitLoop(obj)
+		}
+	}
+}
+
+func isLoopExit(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.ReturnValue, *object.Break, *object.Error:
+		return true
+	default:
+		return false
+	}
+}
+
+func exitLoop(obj object.Object) object.Object {
+	if _, ok := obj.(*object.Break); ok {
+		return nil
+	}
+	return obj
+}
+
+func evalForStatement(
+	statement *ast.ForStatement,
+	env *object.Environment,
+) object.Object {
+	obj := evalExpression(statement.Iterable, env)
+	if object.IsError(obj) {
+		return obj
+	}
+	elements, err := EvalIterable(obj)
+	if err != nil {
+		return locate(err, statement.Iterable)
+	}
+	for _, element := range elements {
+		env.Set(statement.Variable.Value, element)
+		obj = evalBlockStatements(statement.Body, env)
+		if isLoopExit(obj) {
+			return exitLoop(obj)
+		}
+	}
+	return nil
+}
+
 func evalLetStatement(
 	statement *ast.LetStatement,
 	env *object.Environment,
 ) object.Object {
-	env.Set(
-		statement.Name.Value,
-		evalExpression(statement.Value, env),
-	)
+	obj := evalExpression(statement.Value, env)
+	if object.IsError(obj) {
+		return obj
+	}
+	env.Set(statement.Name.Value, obj)
 	return nil
 }
 
@@ -175,37 +326,50 @@ func evalCallExpression(
 	expression *ast.CallExpression,
 	env *object.Environment,
 ) object.Object {
+	if IsQuoteCall(expression) {
+		return evalQuote(expression, env)
+	}
 	function := evalExpression(expression.Function, env)
-	arguments := evalExpressions(expression.Arguments, env)
-	return innerEvalCallExpression(function, arguments)
+	if object.IsError(function) {
+		return function
+	}
+	arguments, err := evalExpressions(expression.Arguments, env)
+	if err != nil {
+		return err
+	}
+	return innerEvalCallExpression(function, arguments, env)
 }
 
 func evalExpressions(
 	expressions []ast.Expression,
 	env *object.Environment,
-) []object.Object {
+) ([]object.Object, *object.Error) {
 	objs := []object.Object{}
 	for _, expression := range expressions {
 		obj := evalExpression(expression, env)
+		if err, ok := obj.(*object.Error); ok {
+			return nil, err
+		}
 		objs = append(objs, obj)
 	}
-	return objs
+	return objs, nil
 }
 
 func innerEvalCallExpression(
 	function object.Object,
 	arguments []object.Object,
+	caller *object.Environment,
 ) object.Object {
 	var obj object.Object
 	switch f := function.(type) {
 	case *object.Function:
-		obj = evalCallExpressionFunction(f, arguments)
+		obj = evalCallExpressionFunction(f, arguments, caller)
 	case *object.Builtin:
 		obj = f.Fn(arguments...)
 	default:
 		message := "cannot evaluate program; " +
-			"unexpected call expression function"
-		log.Fatal(message)
+			"unexpected call expression function %v"
+		obj = object.NewError(message, object.TypeOf(function))
 	}
 	return obj
 }
@@ -213,29 +377,48 @@ func innerEvalCallExpression(
 func evalCallExpressionFunction(
 	function *object.Function,
 	arguments []object.Object,
+	caller *object.Environment,
 ) object.Object {
-	inner := newInnerEnvironment(function, arguments)
+	inner, err := newInnerEnvironment(function, arguments, caller)
+	if err != nil {
+		return err
+	}
 	obj := evalBlockStatements(function.Body, inner)
-	return unwrap(obj)
+	obj = unwrap(obj)
+	if obj == nil {
+		return object.NULL
+	}
+	return obj
 }
 
 func newInnerEnvironment(
 	function *object.Function,
 	arguments []object.Object,
-) *object.Environment {
+	caller *object.Environment,
+) (*object.Environment, *object.Error) {
+	if caller.Depth() >= MaxCallDepth {
+		return nil, object.NewError("cannot evaluate program; stack overflow")
+	}
 	if len(arguments) != len(function.Parameters) {
 		message := "cannot evaluate program; " +
-			"incorrect number of arguments in call expression"
-		log.Fatal(message)
+			"incorrect number of arguments in call expression; " +
+			"got=%v, expected=%v"
+		err := object.NewError(
+			message,
+			len(arguments),
+			len(function.Parameters),
+		)
+		return nil, err
 	}
-	return innerNewInnerEnvironment(function, arguments)
+	return innerNewInnerEnvironment(function, arguments, caller), nil
 }
 
 func innerNewInnerEnvironment(
 	function *object.Function,
 	arguments []object.Object,
+	caller *object.Environment,
 ) *object.Environment {
-	inner := object.NewInnerEnvironment(function.Env)
+	inner := object.NewCallEnvironment(function.Env, caller)
 	for i := 0; i < len(arguments); i++ {
 		inner.Set(
 			function.Parameters[i].Value,
@@ -255,8 +438,11 @@ func unwrap(obj object.Object) object.Object {
 func evalArrayLiteral(
 	expression *ast.ArrayLiteral,
 	env *object.Environment,
-) *object.Array {
-	elements := evalExpressions(expression.Elements, env)
+) object.Object {
+	elements, err := evalExpressions(expression.Elements, env)
+	if err != nil {
+		return err
+	}
 	return &object.Array{Elements: elements}
 }
 
@@ -265,18 +451,94 @@ func evalIndexExpression(
 	env *object.Environment,
 ) object.Object {
 	left := evalExpression(expression.Left, env)
+	if object.IsError(left) {
+		return left
+	}
 	index := evalExpression(expression.Index, env)
+	if object.IsError(index) {
+		return index
+	}
 	return EvalIndex(left, index)
 }
 
+func evalAssignExpression(
+	expression *ast.AssignExpression,
+	env *object.Environment,
+) object.Object {
+	switch target := expression.Target.(type) {
+	case *ast.Identifier:
+		return evalAssignIdentifier(target, expression.Value, env)
+	case *ast.IndexExpression:
+		return evalAssignIndex(target, expression.Value, env)
+	default:
+		message := "cannot evaluate program; unexpected assignment target"
+		return object.NewError(message)
+	}
+}
+
+func evalAssignIdentifier(
+	target *ast.Identifier,
+	expression ast.Expression,
+	env *object.Environment,
+) object.Object {
+	value := evalExpression(expression, env)
+	if object.IsError(value) {
+		return value
+	}
+	if !env.Assign(target.Value, value) {
+		return newAssignError(target, env)
+	}
+	return value
+}
+
+func newAssignError(
+	target *ast.Identifier,
+	env *object.Environment,
+) *object.Error {
+	message := "cannot evaluate program; " +
+		"cannot assign to undefined identifier %v"
+	if _, ok := env.Get(target.Value); ok {
+		message = "cannot evaluate program; cannot assign to built-in %v"
+	}
+	err := object.NewError(message, target.Value)
+	err.Pos = target.Pos()
+	return err
+}
+
+func evalAssignIndex(
+	target *ast.IndexExpression,
+	expression ast.Expression,
+	env *object.Environment,
+) object.Object {
+	left := evalExpression(target.Left, env)
+	if object.IsError(left) {
+		return left
+	}
+	index := evalExpression(target.Index, env)
+	if object.IsError(index) {
+		return index
+	}
+	value := evalExpression(expression, env)
+	if object.IsError(value) {
+		return value
+	}
+	return locate(EvalSetIndex(left, index, value), target)
+}
+
 func evalHashLiteral(
 	expression *ast.HashLiteral,
 	env *object.Environment,
-) *object.Hash {
+) object.Object {
 	pairs := map[object.HashKey]object.HashPair{}
-	for key, value := range expression.Pairs {
-		k := evalHashLiteralKey(key.Expression, env)
-		v := evalExpression(value, env)
+	for _, key := range ast.SortHashKeys(maps.Keys(expression.Pairs)) {
+		k, err := evalHashLiteralKey(key.Expression, env)
+		if err != nil {
+			return err
+		}
+		v := evalExpression(expression.Pairs[key], env)
+		if object.IsError(v) {
+			return v
+		}
 		pairs[k.HashKey()] = object.HashPair{Key: k, Value: v}
 	}
 	return &object.Hash{Pairs: pairs}
@@ -285,7 +547,14 @@ func evalHashLiteral(
 func evalHashLiteralKey(
 	expression ast.Expression,
 	env *object.Environment,
-) object.Hashable {
+) (object.Hashable, *object.Error) {
 	key := evalExpression(expression, env)
-	return object.CastToHashable(key)
+	if err, ok := key.(*object.Error); ok {
+		return nil, err
+	}
+	hash, err := object.CastToHashable(key)
+	if err != nil {
+		locate(err, expression)
+	}
+	return hash, err
 }
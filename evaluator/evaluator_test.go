@@ -28,6 +28,17 @@ func Test(t *testing.T) {
 		{`2 * (5 + 10);`, &object.Integer{Value: 30}},
 		{`3 * 3 * 3 + 10;`, &object.Integer{Value: 37}},
 		{`3 * (3 * 3) + 10;`, &object.Integer{Value: 37}},
+		{`2.5;`, &object.Float{Value: 2.5}},
+		{`-1.5e2;`, &object.Float{Value: -150}},
+		{`1.5 + 1.5;`, &object.Float{Value: 3}},
+		{`1 + 0.5;`, &object.Float{Value: 1.5}},
+		{`0.5 * 4;`, &object.Float{Value: 2}},
+		{`7 / 2.0;`, &object.Float{Value: 3.5}},
+		{`1 - 2.5e-1;`, &object.Float{Value: 0.75}},
+		{`1 == 1.0;`, object.TRUE},
+		{`0.1 + 0.2 != 0.3;`, object.TRUE},
+		{`2 < 2.5;`, object.TRUE},
+		{`{1.5: "a"}[1.5];`, &object.String{Value: "a"}},
 		{`(5 + 10 * 2 + 15 / 3) * 2 + -10;`, &object.Integer{Value: 50}},
 		{`true;`, object.TRUE},
 		{`false;`, object.FALSE},
@@ -155,10 +166,166 @@ func Test(t *testing.T) {
 			`,
 			&object.Integer{Value: 4},
 		},
+		{
+			`
+			let i = 0;
+			let sum = 0;
+			while (i < 10) {
+				let i = i + 1;
+				if (i == 3) { continue; }
+				if (i > 6) { break; }
+				let sum = sum + i;
+			}
+			sum;
+			`,
+			&object.Integer{Value: 18},
+		},
+		{
+			`
+			let sum = fn(xs) {
+				let total = 0;
+				for (x in xs) { let total = total + x; }
+				total;
+			};
+			sum([1, 2, 3]);
+			`,
+			&object.Integer{Value: 6},
+		},
+		{
+			`
+			let keys = [];
+			for (k in {"b": 1, "a": 2, 3: 4}) { let keys = push(keys, k); }
+			keys;
+			`,
+			&object.Array{Elements: []object.Object{
+				&object.Integer{Value: 3},
+				&object.String{Value: "a"},
+				&object.String{Value: "b"},
+			}},
+		},
+		{
+			`
+			let chars = [];
+			for (c in "h\u{e9}") {
+				for (n in [1, 2]) {
+					if (n == 2) { break; }
+					let chars = push(chars, c);
+				}
+			}
+			chars;
+			`,
+			&object.Array{Elements: []object.Object{
+				&object.String{Value: "h"},
+				&object.String{Value: "\u00e9"},
+			}},
+		},
+		{`fn() { while (true) { return 7; } }();`, &object.Integer{Value: 7}},
+		{`fn() { let x = 1; }();`, object.NULL},
+		{`if (true) { let y = 2; };`, object.NULL},
+		{
+			`let n = 0; while (n < 5000) { let n = n + 1; } n;`,
+			&object.Integer{Value: 5000},
+		},
+		{`7 % 3;`, &object.Integer{Value: 1}},
+		{`-7 % 3;`, &object.Integer{Value: -1}},
+		{`7.5 % 2;`, &object.Float{Value: 1.5}},
+		{`2 ** 10;`, &object.Integer{Value: 1024}},
+		{`2 ** 3 ** 2;`, &object.Integer{Value: 512}},
+		{`-2 ** 2;`, &object.Integer{Value: -4}},
+		{`2 ** -1;`, &object.Float{Value: 0.5}},
+		{`4 ** 0.5;`, &object.Float{Value: 2}},
+		{`1 <= 1;`, object.TRUE},
+		{`2 <= 1;`, object.FALSE},
+		{`1 >= 1.5;`, object.FALSE},
+		{`6 & 3;`, &object.Integer{Value: 2}},
+		{`6 | 3;`, &object.Integer{Value: 7}},
+		{`6 ^ 3;`, &object.Integer{Value: 5}},
+		{`1 << 4;`, &object.Integer{Value: 16}},
+		{`-16 >> 2;`, &object.Integer{Value: -4}},
+		{`~5;`, &object.Integer{Value: -6}},
+		{`1 + 1 << 2 | 1;`, &object.Integer{Value: 9}},
+		{`true && false;`, object.FALSE},
+		{`1 && "a";`, object.TRUE},
+		{`false || 0;`, object.TRUE},
+		{`if (false) { 1 } || false;`, object.FALSE},
+		{`1 < 2 && 2 < 3 || false;`, object.TRUE},
+		{`false && 1();`, object.FALSE},
+		{`true || 1();`, object.TRUE},
+		{
+			`let x = 0; false && (x = 1); true || (x = 2); x;`,
+			&object.Integer{Value: 0},
+		},
+		{`let x = 1; x = x + 1; x;`, &object.Integer{Value: 2}},
+		{`let x = 1; let y = x = 5; x + y;`, &object.Integer{Value: 10}},
+		{
+			`let a = [1, 2, 3]; a[1] = 5; a;`,
+			&object.Array{Elements: []object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 5},
+				&object.Integer{Value: 3},
+			}},
+		},
+		{
+			`let h = {"a": 1}; h["b"] = 2; h["a"] = 3; [h["a"], h["b"]];`,
+			&object.Array{Elements: []object.Object{
+				&object.Integer{Value: 3},
+				&object.Integer{Value: 2},
+			}},
+		},
+		{
+			`let g = 0; let f = fn() { g = g + 1; }; f(); f(); g;`,
+			&object.Integer{Value: 2},
+		},
+		{
+			`
+			let counter = fn() { let n = 0; fn() { n = n + 1; } };
+			let c = counter();
+			c();
+			c();
+			c();
+			`,
+			&object.Integer{Value: 3},
+		},
+		{
+			`
+			let f = fn() {
+				let n = 1;
+				let g = fn() { fn() { n = n * 10; } };
+				g()();
+				g()();
+				n;
+			};
+			f();
+			`,
+			&object.Integer{Value: 100},
+		},
+		{
+			`let f = fn(x) { let g = fn() { x = x + 1; }; g(); x; }; f(1);`,
+			&object.Integer{Value: 2},
+		},
+		{
+			`
+			let f = fn(a) { let b = a; let g = fn() { b }; b = b + 1; g(); };
+			f(1) + f(10);
+			`,
+			&object.Integer{Value: 13},
+		},
+		{
+			`
+			fn() {
+				let fs = [];
+				for (i in [1, 2, 3]) { let fs = push(fs, fn() { i }); }
+				fs[0]();
+			}();
+			`,
+			&object.Integer{Value: 3},
+		},
 		{`"Hello World!";`, &object.String{Value: "Hello World!"}},
 		{`"Hello" + " "  + "World!";`, &object.String{Value: "Hello World!"}},
 		{`len("");`, &object.Integer{Value: 0}},
 		{`len("four");`, &object.Integer{Value: 4}},
+		{`len("h\u{e9}llo");`, &object.Integer{Value: 5}},
+		{"`a\\n` + \"\\t\\\"\";", &object.String{Value: "a\\n\t\""}},
 		{`len("hello world");`, &object.Integer{Value: 11}},
 		{`len("hello world");`, &object.Integer{Value: 11}},
 		{
@@ -182,6 +349,7 @@ func Test(t *testing.T) {
 		{`len([]);`, &object.Integer{Value: 0}},
 		{`len([1]);`, &object.Integer{Value: 1}},
 		{`len([1, 2, 3]);`, &object.Integer{Value: 3}},
+		{`args();`, &object.Array{Elements: []object.Object{}}},
 		{`first([]);`, object.NULL},
 		{`first([1]);`, &object.Integer{Value: 1}},
 		{`first([1, 2, 3]);`, &object.Integer{Value: 1}},
@@ -246,6 +414,15 @@ func Test(t *testing.T) {
 		{`{5: 5}[5];`, &object.Integer{Value: 5}},
 		{`{true: 5}[true];`, &object.Integer{Value: 5}},
 		{`{false: 5}[false];`, &object.Integer{Value: 5}},
+		{
+			`
+			// Line comment
+			let a = 1; // Trailing comment
+			/* Block /* nested */ comment */
+			a + /* inline */ 1;
+			`,
+			&object.Integer{Value: 2},
+		},
 	}
 	for _, s := range setup {
 		actual := eval(s.input)
@@ -256,13 +433,18 @@ func Test(t *testing.T) {
 func eval(input string) object.Object {
 	program := parse(input)
 	env := object.NewEnvironment()
-	return Eval(program, env)
+	obj, err := Eval(program, env)
+	if err != nil {
+		return err.(*object.Error)
+	}
+	return obj
 }
 
 func parse(input string) *ast.Program {
 	lex := lexer.New(input)
 	p := parser.New(lex)
-	return p.ParseProgram()
+	program, _ := p.ParseProgram()
+	return program
 }
 
 func testObject(t *testing.T, actual object.Object, expected object.Object) {
@@ -277,6 +459,16 @@ func testObject(t *testing.T, actual object.Object, expected object.Object) {
 			)
 		}
 		testInteger(t, a, e)
+	case *object.Float:
+		a, ok := actual.(*object.Float)
+		if !ok {
+			t.Fatalf(
+				"object type mismatch. got=%T, expected=%T",
+				actual,
+				expected,
+			)
+		}
+		testFloat(t, a, e)
 	case *object.Boolean:
 		a, ok := actual.(*object.Boolean)
 		if !ok {
@@ -317,6 +509,16 @@ func testObject(t *testing.T, actual object.Object, expected object.Object) {
 			)
 		}
 		testHash(t, a, e)
+	case *object.Error:
+		a, ok := actual.(*object.Error)
+		if !ok {
+			t.Fatalf(
+				"object type mismatch. got=%T, expected=%T",
+				actual,
+				expected,
+			)
+		}
+		testError(t, a, e)
 	case *object.Null:
 		_, ok := actual.(*object.Null)
 		if !ok {
@@ -345,6 +547,20 @@ func testInteger(
 	}
 }
 
+func testFloat(
+	t *testing.T,
+	actual *object.Float,
+	expected *object.Float,
+) {
+	if actual.Value != expected.Value {
+		t.Fatalf(
+			"float value mismatch. got=%v, expected=%v",
+			actual.Value,
+			expected.Value,
+		)
+	}
+}
+
 func testBoolean(
 	t *testing.T,
 	actual *object.Boolean,
@@ -420,3 +636,162 @@ func testHashPair(
 	testObject(t, actual.Key, expected.Key)
 	testObject(t, actual.Value, expected.Value)
 }
+
+func testError(
+	t *testing.T,
+	actual *object.Error,
+	expected *object.Error,
+) {
+	if actual.Error() != expected.Error() {
+		t.Fatalf(
+			"error mismatch. got=%q, expected=%q",
+			actual.Error(),
+			expected.Error(),
+		)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`5 + true;`,
+			"1:1: cannot evaluate program; " +
+				"operands Integer and Boolean with operator + " +
+				"aren't of the same type",
+		},
+		{
+			`5 + true; 5;`,
+			"1:1: cannot evaluate program; " +
+				"operands Integer and Boolean with operator + " +
+				"aren't of the same type",
+		},
+		{
+			`-true;`,
+			"1:1: cannot evaluate program; unexpected operand Boolean for - prefix",
+		},
+		{
+			`true + false;`,
+			"1:1: cannot evaluate program; " +
+				"unexpected operator for infix expression Boolean + Boolean",
+		},
+		{
+			`"a" - "b";`,
+			"1:1: cannot evaluate program; " +
+				"unexpected operator for infix expression String - String",
+		},
+		{
+			"if (10 > 1) {\n  if (10 > 1) { return true + false; }\n  return 1;\n}",
+			"2:24: cannot evaluate program; " +
+				"unexpected operator for infix expression Boolean + Boolean",
+		},
+		{
+			`foobar;`,
+			"1:1: cannot evaluate program; " +
+				"encountered undefined identifier foobar",
+		},
+		{
+			`let f = fn(x) { x; }; f(1, 2);`,
+			"1:23: cannot evaluate program; " +
+				"incorrect number of arguments in call expression; " +
+				"got=2, expected=1",
+		},
+		{
+			`let a = 1; a(); 2;`,
+			"1:12: cannot evaluate program; " +
+				"unexpected call expression function Integer",
+		},
+		{
+			`{fn(x) { x }: 1};`,
+			"1:2: cannot cast to hashable; " +
+				"unexpected object Function encountered",
+		},
+		{
+			`[1, 2][true];`,
+			"1:1: cannot evaluate program; " +
+				"unexpected index Boolean in index expression",
+		},
+		{
+			`len(1);`,
+			"1:1: cannot call built-in; invalid argument Integer",
+		},
+		{
+			`1 / 0;`,
+			"1:1: cannot evaluate program; " +
+				"division by zero with operator /",
+		},
+		{
+			`1.5 % 0;`,
+			"1:1: cannot evaluate program; " +
+				"division by zero with operator %",
+		},
+		{
+			`1 << -1;`,
+			"1:1: cannot evaluate program; " +
+				"negative shift count -1 with operator <<",
+		},
+		{
+			`~1.5;`,
+			"1:1: cannot evaluate program; unexpected operand Float for ~ prefix",
+		},
+		{
+			`1.5 & 1;`,
+			"1:1: cannot evaluate program; " +
+				"unexpected operator for infix expression Float & Float",
+		},
+		{
+			`quote(1, 2);`,
+			"1:1: cannot evaluate program; " +
+				"quote expects exactly one argument; got=2",
+		},
+		{
+			`quote(unquote(x));`,
+			"1:15: cannot evaluate program; " +
+				"encountered undefined identifier x",
+		},
+		{
+			`let m = macro(x) { x }; m(1);`,
+			"1:9: cannot evaluate program; " +
+				"macro must be bound by a top-level let statement",
+		},
+		{
+			`let m = import "m.mk";`,
+			"1:9: cannot evaluate program; imports aren't available",
+		},
+		{
+			`x = 1;`,
+			"1:1: cannot evaluate program; " +
+				"cannot assign to undefined identifier x",
+		},
+		{
+			`len = 1;`,
+			"1:1: cannot evaluate program; cannot assign to built-in len",
+		},
+		{
+			"let f = fn() { f() };\nf();",
+			"1:16: cannot evaluate program; stack overflow",
+		},
+		{
+			`let a = [1]; a[3] = 2;`,
+			"1:14: cannot evaluate program; " +
+				"index 3 out of range in index assignment",
+		},
+		{
+			`let a = 1; a[0] = 2;`,
+			"1:12: cannot evaluate program; " +
+				"unexpected left Integer in index assignment",
+		},
+		{
+			`let f = fn() { [1, x]; }; f();`,
+			"1:20: cannot evaluate program; " +
+				"encountered undefined identifier x",
+		},
+	}
+
+	for _, s := range setup {
+		actual := eval(s.input)
+		testObject(t, actual, &object.Error{Message: s.expected})
+	}
+}
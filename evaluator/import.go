@@ -0,0 +1,106 @@
+package evaluator
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/module"
+	"github.com/vincentlabelle/monkey/object"
+)
+
+type Importer struct {
+	loader    *module.Loader
+	modules   map[string]*object.Hash
+	loading   []string
+	arguments []string
+}
+
+func NewImporter(loader *module.Loader) *Importer {
+	return &Importer{
+		loader:    loader,
+		modules:   map[string]*object.Hash{},
+		loading:   []string{},
+		arguments: []string{},
+	}
+}
+
+func (i *Importer) SetArguments(values []string) {
+	i.arguments = values
+}
+
+func (i *Importer) Import(from string, name string) object.Object {
+	resolved, err := i.loader.Resolve(from, name)
+	if err != nil {
+		return object.NewError("%v", err)
+	}
+	if namespace, ok := i.modules[resolved]; ok {
+		return namespace
+	}
+	if slices.Contains(i.loading, resolved) {
+		cycle := append(slices.Clone(i.loading), resolved)
+		message := "cannot import; import cycle %v"
+		return object.NewError(message, strings.Join(cycle, " -> "))
+	}
+	return i.load(resolved)
+}
+
+func (i *Importer) load(name string) object.Object {
+	i.loading = append(i.loading, name)
+	defer func() { i.loading = i.loading[:len(i.loading)-1] }()
+	program, err := LoadModule(i.loader, name)
+	if err != nil {
+		return object.NewError("%v", err)
+	}
+	env := object.NewEnvironment()
+	env.SetImporter(i)
+	env.SetArguments(i.arguments)
+	if obj := evalProgram(program, env); object.IsError(obj) {
+		return obj
+	}
+	namespace := newNamespace(program, env)
+	i.modules[name] = namespace
+	return namespace
+}
+
+func LoadModule(loader *module.Loader, name string) (*ast.Program, error) {
+	program, err := loader.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	macros := object.NewEnvironment()
+	DefineMacros(program, macros)
+	return ExpandMacros(program, macros)
+}
+
+func Exports(program *ast.Program) []*ast.Identifier {
+	exports := []*ast.Identifier{}
+	for _, statement := range program.Statements {
+		if let, ok := statement.(*ast.LetStatement); ok && let.Exported {
+			exports = append(exports, let.Name)
+		}
+	}
+	return exports
+}
+
+func newNamespace(program *ast.Program, env *object.Environment) *object.Hash {
+	pairs := map[object.HashKey]object.HashPair{}
+	for _, name := range Exports(program) {
+		key := object.NativeToString(name.Value)
+		value, _ := env.Get(name.Value)
+		pairs[key.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+	return &object.Hash{Pairs: pairs}
+}
+
+func evalImportExpression(
+	expression *ast.ImportExpression,
+	env *object.Environment,
+) object.Object {
+	importer := env.Importer()
+	if importer == nil {
+		message := "cannot evaluate program; imports aren't available"
+		return object.NewError(message)
+	}
+	return importer.Import(expression.Pos().File, expression.Path)
+}
@@ -0,0 +1,109 @@
+package evaluator
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/vincentlabelle/monkey/lexer"
+	"github.com/vincentlabelle/monkey/module"
+	"github.com/vincentlabelle/monkey/object"
+	"github.com/vincentlabelle/monkey/parser"
+)
+
+var modules = fstest.MapFS{
+	"lib/counter.mk": {Data: []byte(`
+		let count = 0;
+		export let next = fn() { count = count + 1; count };
+		export let name = "counter";
+	`)},
+	"lib/twice.mk": {Data: []byte(`
+		let counter = import "counter.mk";
+		export let next = fn() { counter.next(); counter.next() };
+	`)},
+	"a.mk":      {Data: []byte(`export let b = import "./b.mk";`)},
+	"b.mk":      {Data: []byte(`export let a = import "./a.mk";`)},
+	"broken.mk": {Data: []byte(`export let x = 1 + true;`)},
+}
+
+func TestImport(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected object.Object
+	}{
+		{
+			`let c = import "counter.mk"; c.name;`,
+			object.NativeToString("counter"),
+		},
+		{
+			`let c = import "counter.mk"; c.next(); c.next();`,
+			&object.Integer{Value: 2},
+		},
+		{
+			`let c = import "counter.mk"; c["count"];`,
+			object.NULL,
+		},
+		{
+			`let c = import "counter.mk";
+			let d = import "./lib/counter.mk";
+			c.next();
+			d.next();`,
+			&object.Integer{Value: 2},
+		},
+		{
+			`let t = import "twice.mk";
+			let c = import "counter.mk";
+			t.next();
+			c.next();`,
+			&object.Integer{Value: 3},
+		},
+	}
+
+	for _, s := range setup {
+		actual := evalMain(s.input)
+		testObject(t, actual, s.expected)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`import "missing.mk";`,
+			`/main.mk:1:1: cannot import; cannot find module "missing.mk"`,
+		},
+		{
+			`import "./a.mk";`,
+			"/b.mk:1:16: cannot import; " +
+				"import cycle /a.mk -> /b.mk -> /a.mk",
+		},
+		{
+			`let x = import "./broken.mk"; 1;`,
+			"/broken.mk:1:16: cannot evaluate program; " +
+				"operands Integer and Boolean with operator + " +
+				"aren't of the same type",
+		},
+	}
+
+	for _, s := range setup {
+		actual, ok := evalMain(s.input).(*object.Error)
+		if !ok {
+			t.Fatalf("expected error. expected=%q", s.expected)
+		}
+		testError(t, actual, &object.Error{Message: s.expected})
+	}
+}
+
+func evalMain(input string) object.Object {
+	lex := lexer.NewFile("/main.mk", input)
+	p := parser.New(lex)
+	program, _ := p.ParseProgram()
+	env := object.NewEnvironment()
+	env.SetImporter(NewImporter(module.NewLoader(modules, "/lib")))
+	obj, err := Eval(program, env)
+	if err != nil {
+		return err.(*object.Error)
+	}
+	return obj
+}
@@ -0,0 +1,140 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/object"
+	"github.com/vincentlabelle/monkey/token"
+)
+
+type ExpansionError struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e *ExpansionError) Error() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Message
+	}
+	return e.Message
+}
+
+func newExpansionError(
+	node ast.Node,
+	format string,
+	a ...any,
+) *ExpansionError {
+	message := "cannot expand macro; " + fmt.Sprintf(format, a...)
+	return &ExpansionError{Pos: node.Pos(), Message: message}
+}
+
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	statements := []ast.Statement{}
+	for _, statement := range program.Statements {
+		if !defineMacro(statement, env) {
+			statements = append(statements, statement)
+		}
+	}
+	program.Statements = statements
+}
+
+func defineMacro(statement ast.Statement, env *object.Environment) bool {
+	let, ok := statement.(*ast.LetStatement)
+	if !ok {
+		return false
+	}
+	literal, ok := let.Value.(*ast.MacroLiteral)
+	if !ok {
+		return false
+	}
+	macro := &object.Macro{
+		Parameters: literal.Parameters,
+		Body:       literal.Body,
+		Env:        env,
+	}
+	env.Set(let.Name.Value, macro)
+	return true
+}
+
+func ExpandMacros(
+	program *ast.Program,
+	env *object.Environment,
+) (*ast.Program, error) {
+	var err *ExpansionError
+	expanded := ast.Rewrite(program, func(node ast.Node) ast.Node {
+		if err != nil {
+			return node
+		}
+		call, macro, ok := getMacroCall(node, env)
+		if !ok {
+			return node
+		}
+		var expansion ast.Node
+		expansion, err = expandMacroCall(call, macro)
+		return expansion
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expanded.(*ast.Program), nil
+}
+
+func getMacroCall(
+	node ast.Node,
+	env *object.Environment,
+) (*ast.CallExpression, *object.Macro, bool) {
+	call, ok := node.(*ast.CallExpression)
+	if !ok {
+		return nil, nil, false
+	}
+	identifier, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil, nil, false
+	}
+	obj, ok := env.Get(identifier.Value)
+	if !ok {
+		return nil, nil, false
+	}
+	macro, ok := obj.(*object.Macro)
+	return call, macro, ok
+}
+
+func expandMacroCall(
+	call *ast.CallExpression,
+	macro *object.Macro,
+) (ast.Node, *ExpansionError) {
+	if len(call.Arguments) != len(macro.Parameters) {
+		message := "incorrect number of arguments in macro call; " +
+			"got=%v, expected=%v"
+		err := newExpansionError(
+			call,
+			message,
+			len(call.Arguments),
+			len(macro.Parameters),
+		)
+		return call, err
+	}
+	env := newMacroEnvironment(call, macro)
+	obj := unwrap(evalBlockStatements(macro.Body, env))
+	if err, ok := obj.(*object.Error); ok {
+		return call, newExpansionError(call, "%v", err)
+	}
+	quote, ok := obj.(*object.Quote)
+	if !ok {
+		message := "macro must return a quote; got=%v"
+		return call, newExpansionError(call, message, object.TypeOf(obj))
+	}
+	return quote.Node, nil
+}
+
+func newMacroEnvironment(
+	call *ast.CallExpression,
+	macro *object.Macro,
+) *object.Environment {
+	env := object.NewInnerEnvironment(macro.Env)
+	for i, parameter := range macro.Parameters {
+		env.Set(parameter.Value, &object.Quote{Node: call.Arguments[i]})
+	}
+	return env
+}
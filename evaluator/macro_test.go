@@ -0,0 +1,230 @@
+package evaluator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/object"
+)
+
+func TestQuote(t *testing.T) {
+	setup := []struct {
+		input        string
+		expectedType string
+		expected     object.Object
+	}{
+		{`quote(5);`, "*ast.IntegerLiteral", &object.Integer{Value: 5}},
+		{`quote(5 + 8);`, "*ast.InfixExpression", &object.Integer{Value: 13}},
+		{
+			`quote(unquote(4 + 4));`,
+			"*ast.IntegerLiteral",
+			&object.Integer{Value: 8},
+		},
+		{
+			`let x = 8; quote(unquote(x) * 2);`,
+			"*ast.InfixExpression",
+			&object.Integer{Value: 16},
+		},
+		{
+			`let q = quote(4 + 4); quote(unquote(q) * 2);`,
+			"*ast.InfixExpression",
+			&object.Integer{Value: 16},
+		},
+		{`quote(unquote(1.5 < 1));`, "*ast.BooleanLiteral", object.FALSE},
+		{
+			`let f = fn(x) { quote(unquote(x) + 1) }; f(1); f(2);`,
+			"*ast.InfixExpression",
+			&object.Integer{Value: 3},
+		},
+	}
+
+	for _, s := range setup {
+		quote, ok := eval(s.input).(*object.Quote)
+		if !ok {
+			t.Fatalf("object type mismatch. expected=*object.Quote")
+		}
+		if actual := fmt.Sprintf("%T", quote.Node); actual != s.expectedType {
+			t.Fatalf(
+				"node type mismatch. got=%v, expected=%v",
+				actual,
+				s.expectedType,
+			)
+		}
+		testObject(t, evalNode(quote.Node), s.expected)
+	}
+}
+
+func TestQuoteInspect(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(5 + 8);`, "quote(5 + 8)"},
+		{`let q = quote(4 + 4); quote(unquote(q) * 2);`, "quote((4 + 4) * 2)"},
+		{`quote(unquote(-1) ** 2);`, "quote((-1) ** 2)"},
+		{`quote(fn(x) { x });`, "quote(fn(x) {\n    x;\n})"},
+	}
+
+	for _, s := range setup {
+		actual := eval(s.input).Inspect()
+		if actual != s.expected {
+			t.Fatalf("inspect mismatch. got=%q, expected=%q", actual, s.expected)
+		}
+	}
+}
+
+func evalNode(node ast.Node) object.Object {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.ExpressionStatement{Expression: node.(ast.Expression)},
+		},
+	}
+	obj, err := Eval(program, object.NewEnvironment())
+	if err != nil {
+		return err.(*object.Error)
+	}
+	return obj
+}
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+	let number = 1;
+	let function = fn(x, y) { x + y };
+	let mymacro = macro(x, y) { x + y; };
+	`
+	program := parse(input)
+	env := object.NewEnvironment()
+	DefineMacros(program, env)
+	if len(program.Statements) != 2 {
+		t.Fatalf(
+			"number of statements mismatch. got=%v, expected=2",
+			len(program.Statements),
+		)
+	}
+	for _, name := range []string{"number", "function"} {
+		if _, ok := env.Get(name); ok {
+			t.Fatalf("unexpected definition of %v", name)
+		}
+	}
+	obj, _ := env.Get("mymacro")
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		t.Fatalf("object type mismatch. got=%T, expected=*object.Macro", obj)
+	}
+	if len(macro.Parameters) != 2 {
+		t.Fatalf(
+			"number of parameters mismatch. got=%v, expected=2",
+			len(macro.Parameters),
+		)
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected object.Object
+	}{
+		{
+			`
+			let infix = macro() { quote(1 + 2); };
+			infix();
+			`,
+			&object.Integer{Value: 3},
+		},
+		{
+			`
+			let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+			reverse(2 + 2, 10 - 5);
+			`,
+			&object.Integer{Value: 1},
+		},
+		{
+			`
+			let unless = macro(condition, consequence, alternative) {
+				quote(if (!(unquote(condition))) {
+					unquote(consequence);
+				} else {
+					unquote(alternative);
+				});
+			};
+			unless(10 > 5, 1(), "greater");
+			`,
+			&object.String{Value: "greater"},
+		},
+		{
+			`
+			let twice = macro(x) { quote(unquote(x) + unquote(x)) };
+			let f = fn() { twice(twice(2)) };
+			f();
+			`,
+			&object.Integer{Value: 8},
+		},
+	}
+
+	for _, s := range setup {
+		actual := expand(t, s.input)
+		testObject(t, actual, s.expected)
+	}
+}
+
+func expand(t *testing.T, input string) object.Object {
+	program := parse(input)
+	env := object.NewEnvironment()
+	DefineMacros(program, env)
+	expanded, err := ExpandMacros(program, env)
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	obj, err := Eval(expanded, object.NewEnvironment())
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	return obj
+}
+
+func TestExpansionErrors(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let m = macro(x) { quote(x) };\nm(1, 2);",
+			"2:1: cannot expand macro; " +
+				"incorrect number of arguments in macro call; " +
+				"got=2, expected=1",
+		},
+		{
+			"let m = macro() { 1 };\nm();",
+			"2:1: cannot expand macro; macro must return a quote; got=Integer",
+		},
+		{
+			"let m = macro(x) { quote(unquote(x + 1)) };\nm(1);",
+			"2:1: cannot expand macro; 1:34: cannot evaluate program; " +
+				"operands Quote and Integer with operator + " +
+				"aren't of the same type",
+		},
+		{
+			"let m = macro(x) { quote(unquote(len)) };\nm(1);",
+			"2:1: cannot expand macro; " +
+				"1:26: cannot evaluate program; cannot unquote Builtin",
+		},
+	}
+
+	for _, s := range setup {
+		program := parse(s.input)
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		_, err := ExpandMacros(program, env)
+		if err == nil {
+			t.Fatalf("expected error. expected=%q", s.expected)
+		}
+		if err.Error() != s.expected {
+			t.Fatalf(
+				"error mismatch. got=%q, expected=%q",
+				err.Error(),
+				s.expected,
+			)
+		}
+	}
+}
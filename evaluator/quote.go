@@ -0,0 +1,152 @@
+package evaluator
+
+import (
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/object"
+)
+
+func IsQuoteCall(expression *ast.CallExpression) bool {
+	return isCallTo(expression, "quote")
+}
+
+func isCallTo(expression ast.Node, name string) bool {
+	call, ok := expression.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+	identifier, ok := call.Function.(*ast.Identifier)
+	return ok && identifier.Value == name
+}
+
+func IsUnquoteCall(node ast.Node) bool {
+	return isCallTo(node, "unquote")
+}
+
+func FindUnquoteCall(quoted ast.Node) (ast.Node, bool) {
+	var found ast.Node
+	ast.Inspect(quoted, func(node ast.Node) bool {
+		if found == nil && IsUnquoteCall(node) {
+			found = node
+		}
+		return found == nil
+	})
+	return found, found != nil
+}
+
+func evalQuote(
+	expression *ast.CallExpression,
+	env *object.Environment,
+) object.Object {
+	if len(expression.Arguments) != 1 {
+		message := "cannot evaluate program; " +
+			"quote expects exactly one argument; got=%v"
+		return object.NewError(message, len(expression.Arguments))
+	}
+	node, err := evalUnquoteCalls(expression.Arguments[0], env)
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: node}
+}
+
+func EvalUnquote(
+	quote *object.Quote,
+	values []object.Object,
+) object.Object {
+	count := 0
+	node, err := unquoteCalls(
+		quote.Node,
+		func(call *ast.CallExpression) (ast.Node, *object.Error) {
+			count++
+			if count > len(values) {
+				return call, newUnquoteCountError(len(values))
+			}
+			return convertObjectToNode(values[count-1], call)
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if count != len(values) {
+		return newUnquoteCountError(len(values))
+	}
+	return &object.Quote{Node: node}
+}
+
+func newUnquoteCountError(count int) *object.Error {
+	message := "cannot evaluate program; " +
+		"unexpected number of unquoted values; got=%v"
+	return object.NewError(message, count)
+}
+
+func evalUnquoteCalls(
+	quoted ast.Node,
+	env *object.Environment,
+) (ast.Node, *object.Error) {
+	return unquoteCalls(
+		quoted,
+		func(call *ast.CallExpression) (ast.Node, *object.Error) {
+			return evalUnquoteCall(call, env)
+		},
+	)
+}
+
+func unquoteCalls(
+	quoted ast.Node,
+	unquote func(*ast.CallExpression) (ast.Node, *object.Error),
+) (ast.Node, *object.Error) {
+	var err *object.Error
+	node := ast.Rewrite(quoted, func(node ast.Node) ast.Node {
+		if err != nil || !IsUnquoteCall(node) {
+			return node
+		}
+		var unquoted ast.Node
+		unquoted, err = unquote(node.(*ast.CallExpression))
+		return unquoted
+	})
+	return node, err
+}
+
+func evalUnquoteCall(
+	call *ast.CallExpression,
+	env *object.Environment,
+) (ast.Node, *object.Error) {
+	if len(call.Arguments) != 1 {
+		message := "cannot evaluate program; " +
+			"unquote expects exactly one argument; got=%v"
+		err := object.NewError(message, len(call.Arguments))
+		return call, locate(err, call).(*object.Error)
+	}
+	obj := evalExpression(call.Arguments[0], env)
+	if err, ok := obj.(*object.Error); ok {
+		return call, err
+	}
+	node, err := convertObjectToNode(obj, call)
+	if err != nil {
+		return call, locate(err, call).(*object.Error)
+	}
+	return node, nil
+}
+
+func convertObjectToNode(
+	obj object.Object,
+	call *ast.CallExpression,
+) (ast.Node, *object.Error) {
+	var node ast.Node
+	switch o := obj.(type) {
+	case *object.Integer:
+		node = &ast.IntegerLiteral{Span: call.Span, Value: o.Value}
+	case *object.Float:
+		node = &ast.FloatLiteral{Span: call.Span, Value: o.Value}
+	case *object.Boolean:
+		node = &ast.BooleanLiteral{Span: call.Span, Value: o.Value}
+	case *object.String:
+		node = &ast.StringLiteral{Span: call.Span, Value: o.Value}
+	case *object.Quote:
+		node = o.Node
+	default:
+		message := "cannot evaluate program; cannot unquote %v"
+		return call, object.NewError(message, object.TypeOf(obj))
+	}
+	return node, nil
+}
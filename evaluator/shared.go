@@ -1,8 +1,9 @@
 package evaluator
 
 import (
-	"log"
-	"reflect"
+	"cmp"
+	"math"
+	"slices"
 
 	"github.com/vincentlabelle/monkey/object"
 )
@@ -17,22 +18,26 @@ func EvalPrefix(
 		obj = evalMinusPrefix(right)
 	case "!":
 		obj = evalBangPrefix(right)
+	case "~":
+		obj = evalTildePrefix(right)
 	default:
-		message := "cannot evaluate program; unexpected prefix operator"
-		log.Fatal(message)
+		message := "cannot evaluate program; unexpected prefix operator %v"
+		obj = object.NewError(message, operator)
 	}
 	return obj
 }
 
-func evalMinusPrefix(obj object.Object) *object.Integer {
+func evalMinusPrefix(obj object.Object) object.Object {
 	switch o := obj.(type) {
 	case *object.Integer:
 		return object.NativeToInteger(-o.Value)
+	case *object.Float:
+		return object.NativeToFloat(-o.Value)
 	default:
-		message := "cannot evaluate program; unexpected operand for - prefix"
-		log.Fatal(message)
+		message := "cannot evaluate program; " +
+			"unexpected operand %v for - prefix"
+		return object.NewError(message, object.TypeOf(obj))
 	}
-	return nil
 }
 
 func evalBangPrefix(obj object.Object) *object.Boolean {
@@ -48,6 +53,14 @@ func evalBangPrefix(obj object.Object) *object.Boolean {
 	return new_
 }
 
+func evalTildePrefix(obj object.Object) object.Object {
+	if o, ok := obj.(*object.Integer); ok {
+		return object.NativeToInteger(^o.Value)
+	}
+	message := "cannot evaluate program; unexpected operand %v for ~ prefix"
+	return object.NewError(message, object.TypeOf(obj))
+}
+
 func EvalInfix(
 	left object.Object,
 	operator string,
@@ -57,6 +70,8 @@ func EvalInfix(
 	if object.IsType(left, "Integer") && object.IsType(right, "Integer") {
 		l, r := left.(*object.Integer), right.(*object.Integer)
 		obj = evalIntegerInfix(l, operator, r)
+	} else if l, r, ok := castToFloats(left, right); ok {
+		obj = evalFloatInfix(l, operator, r)
 	} else if object.IsType(left, "String") && object.IsType(right, "String") {
 		l, r := left.(*object.String), right.(*object.String)
 		obj = evalStringInfix(l, operator, r)
@@ -64,18 +79,36 @@ func EvalInfix(
 		obj = object.NativeToBoolean(left == right)
 	} else if operator == "!=" {
 		obj = object.NativeToBoolean(left != right)
-	} else if reflect.TypeOf(left) != reflect.TypeOf(right) {
+	} else if object.TypeOf(left) != object.TypeOf(right) {
 		message := "cannot evaluate program; " +
-			"operands with operator %v aren't of the same type"
-		log.Fatalf(message, operator)
+			"operands %v and %v with operator %v aren't of the same type"
+		obj = object.NewError(
+			message,
+			object.TypeOf(left),
+			object.TypeOf(right),
+			operator,
+		)
 	} else {
-		message := "cannot evaluate program; " +
-			"unexpected operator for infix expression"
-		log.Fatal(message)
+		obj = newInfixOperatorError(left, operator, right)
 	}
 	return obj
 }
 
+func newInfixOperatorError(
+	left object.Object,
+	operator string,
+	right object.Object,
+) *object.Error {
+	message := "cannot evaluate program; " +
+		"unexpected operator for infix expression %v %v %v"
+	return object.NewError(
+		message,
+		object.TypeOf(left),
+		operator,
+		object.TypeOf(right),
+	)
+}
+
 func evalIntegerInfix(
 	left *object.Integer,
 	operator string,
@@ -89,37 +122,188 @@ func evalIntegerInfix(
 		obj = object.NativeToInteger(left.Value - right.Value)
 	case "*":
 		obj = object.NativeToInteger(left.Value * right.Value)
-	case "/":
-		obj = object.NativeToInteger(left.Value / right.Value)
+	case "/", "%":
+		obj = evalIntegerDivision(left, operator, right)
+	case "**":
+		obj = evalIntegerPower(left, right)
+	case "&", "|", "^", "<<", ">>":
+		obj = evalIntegerBitwise(left, operator, right)
 	case "<":
 		obj = object.NativeToBoolean(left.Value < right.Value)
 	case ">":
 		obj = object.NativeToBoolean(left.Value > right.Value)
+	case "<=":
+		obj = object.NativeToBoolean(left.Value <= right.Value)
+	case ">=":
+		obj = object.NativeToBoolean(left.Value >= right.Value)
 	case "==":
 		obj = object.NativeToBoolean(left.Value == right.Value)
 	case "!=":
 		obj = object.NativeToBoolean(left.Value != right.Value)
 	default:
+		obj = newInfixOperatorError(left, operator, right)
+	}
+	return obj
+}
+
+func evalIntegerDivision(
+	left *object.Integer,
+	operator string,
+	right *object.Integer,
+) object.Object {
+	if right.Value == 0 {
+		return newDivisionByZeroError(operator)
+	}
+	if operator == "%" {
+		return object.NativeToInteger(left.Value % right.Value)
+	}
+	return object.NativeToInteger(left.Value / right.Value)
+}
+
+func newDivisionByZeroError(operator string) *object.Error {
+	message := "cannot evaluate program; division by zero with operator %v"
+	return object.NewError(message, operator)
+}
+
+func evalIntegerPower(
+	left *object.Integer,
+	right *object.Integer,
+) object.Object {
+	if right.Value < 0 {
+		power := math.Pow(float64(left.Value), float64(right.Value))
+		return object.NativeToFloat(power)
+	}
+	power, base := 1, left.Value
+	for exponent := right.Value; exponent > 0; exponent >>= 1 {
+		if exponent&1 == 1 {
+			power *= base
+		}
+		base *= base
+	}
+	return object.NativeToInteger(power)
+}
+
+func evalIntegerBitwise(
+	left *object.Integer,
+	operator string,
+	right *object.Integer,
+) object.Object {
+	var obj object.Object
+	switch operator {
+	case "&":
+		obj = object.NativeToInteger(left.Value & right.Value)
+	case "|":
+		obj = object.NativeToInteger(left.Value | right.Value)
+	case "^":
+		obj = object.NativeToInteger(left.Value ^ right.Value)
+	default:
+		obj = evalIntegerShift(left, operator, right)
+	}
+	return obj
+}
+
+func evalIntegerShift(
+	left *object.Integer,
+	operator string,
+	right *object.Integer,
+) object.Object {
+	if right.Value < 0 {
 		message := "cannot evaluate program; " +
-			"unexpected operator for infix expression"
-		log.Fatal(message)
+			"negative shift count %v with operator %v"
+		return object.NewError(message, right.Value, operator)
+	}
+	if operator == "<<" {
+		return object.NativeToInteger(left.Value << right.Value)
+	}
+	return object.NativeToInteger(left.Value >> right.Value)
+}
+
+func castToFloats(
+	left object.Object,
+	right object.Object,
+) (*object.Float, *object.Float, bool) {
+	l, ok := castToFloat(left)
+	if !ok {
+		return nil, nil, false
+	}
+	r, ok := castToFloat(right)
+	if !ok {
+		return nil, nil, false
+	}
+	return l, r, true
+}
+
+func castToFloat(obj object.Object) (*object.Float, bool) {
+	switch o := obj.(type) {
+	case *object.Float:
+		return o, true
+	case *object.Integer:
+		return object.NativeToFloat(float64(o.Value)), true
+	default:
+		return nil, false
+	}
+}
+
+func evalFloatInfix(
+	left *object.Float,
+	operator string,
+	right *object.Float,
+) object.Object {
+	var obj object.Object
+	switch operator {
+	case "+":
+		obj = object.NativeToFloat(left.Value + right.Value)
+	case "-":
+		obj = object.NativeToFloat(left.Value - right.Value)
+	case "*":
+		obj = object.NativeToFloat(left.Value * right.Value)
+	case "/", "%":
+		obj = evalFloatDivision(left, operator, right)
+	case "**":
+		obj = object.NativeToFloat(math.Pow(left.Value, right.Value))
+	case "<":
+		obj = object.NativeToBoolean(left.Value < right.Value)
+	case ">":
+		obj = object.NativeToBoolean(left.Value > right.Value)
+	case "<=":
+		obj = object.NativeToBoolean(left.Value <= right.Value)
+	case ">=":
+		obj = object.NativeToBoolean(left.Value >= right.Value)
+	case "==":
+		obj = object.NativeToBoolean(left.Value == right.Value)
+	case "!=":
+		obj = object.NativeToBoolean(left.Value != right.Value)
+	default:
+		obj = newInfixOperatorError(left, operator, right)
 	}
 	return obj
 }
 
+func evalFloatDivision(
+	left *object.Float,
+	operator string,
+	right *object.Float,
+) object.Object {
+	if right.Value == 0 {
+		return newDivisionByZeroError(operator)
+	}
+	if operator == "%" {
+		return object.NativeToFloat(math.Mod(left.Value, right.Value))
+	}
+	return object.NativeToFloat(left.Value / right.Value)
+}
+
 func evalStringInfix(
 	left *object.String,
 	operator string,
 	right *object.String,
-) *object.String {
-	var obj *object.String
+) object.Object {
+	var obj object.Object
 	switch operator {
 	case "+":
 		obj = object.NativeToString(left.Value + right.Value)
 	default:
-		message := "cannot evaluate program; " +
-			"unexpected operator for infix expression"
-		log.Fatal(message)
+		obj = newInfixOperatorError(left, operator, right)
 	}
 	return obj
 }
@@ -135,6 +319,55 @@ func EvalTruthy(obj object.Object) *object.Boolean {
 	}
 }
 
+func EvalIterable(obj object.Object) ([]object.Object, *object.Error) {
+	switch o := obj.(type) {
+	case *object.Array:
+		return slices.Clone(o.Elements), nil
+	case *object.Hash:
+		return evalHashKeys(o), nil
+	case *object.String:
+		return evalStringCharacters(o), nil
+	default:
+		message := "cannot evaluate program; unexpected iterable %v"
+		return nil, object.NewError(message, object.TypeOf(obj))
+	}
+}
+
+func evalHashKeys(hash *object.Hash) []object.Object {
+	keys := []object.Object{}
+	for _, pair := range hash.Pairs {
+		keys = append(keys, pair.Key)
+	}
+	slices.SortFunc(keys, compareHashKeys)
+	return keys
+}
+
+func compareHashKeys(x object.Object, y object.Object) int {
+	if c := cmp.Compare(object.TypeOf(x), object.TypeOf(y)); c != 0 {
+		return c
+	}
+	switch k := x.(type) {
+	case *object.Integer:
+		return cmp.Compare(k.Value, y.(*object.Integer).Value)
+	case *object.Float:
+		return cmp.Compare(k.Value, y.(*object.Float).Value)
+	case *object.String:
+		return cmp.Compare(k.Value, y.(*object.String).Value)
+	case *object.Boolean:
+		return cmp.Compare(k.HashKey().Value, y.(*object.Boolean).HashKey().Value)
+	default:
+		return 0
+	}
+}
+
+func evalStringCharacters(str *object.String) []object.Object {
+	characters := []object.Object{}
+	for _, char := range str.Value {
+		characters = append(characters, object.NativeToString(string(char)))
+	}
+	return characters
+}
+
 func EvalIndex(
 	left object.Object,
 	index object.Object,
@@ -147,8 +380,8 @@ func EvalIndex(
 		obj = evalHashIndex(l, index)
 	default:
 		message := "cannot evaluate program; " +
-			"unexpected left in index expression"
-		log.Fatal(message)
+			"unexpected left %v in index expression"
+		obj = object.NewError(message, object.TypeOf(left))
 	}
 	return obj
 }
@@ -159,13 +392,17 @@ func evalArrayIndex(
 ) object.Object {
 	i, ok := index.(*object.Integer)
 	if !ok {
-		message := "cannot evaluate program; " +
-			"unexpected index in index expression"
-		log.Fatal(message)
+		return newIndexError(index)
 	}
 	return innerEvalArrayIndex(left, i)
 }
 
+func newIndexError(index object.Object) *object.Error {
+	message := "cannot evaluate program; " +
+		"unexpected index %v in index expression"
+	return object.NewError(message, object.TypeOf(index))
+}
+
 func innerEvalArrayIndex(
 	left *object.Array,
 	index *object.Integer,
@@ -182,9 +419,7 @@ func evalHashIndex(
 ) object.Object {
 	i, ok := index.(object.Hashable)
 	if !ok {
-		message := "cannot evaluate program; " +
-			"unexpected index in index expression"
-		log.Fatal(message)
+		return newIndexError(index)
 	}
 	return innerEvalHashIndex(left, i)
 }
@@ -198,3 +433,53 @@ func innerEvalHashIndex(
 	}
 	return object.NULL
 }
+
+func EvalSetIndex(
+	left object.Object,
+	index object.Object,
+	value object.Object,
+) object.Object {
+	var obj object.Object
+	switch l := left.(type) {
+	case *object.Array:
+		obj = evalSetArrayIndex(l, index, value)
+	case *object.Hash:
+		obj = evalSetHashIndex(l, index, value)
+	default:
+		message := "cannot evaluate program; " +
+			"unexpected left %v in index assignment"
+		obj = object.NewError(message, object.TypeOf(left))
+	}
+	return obj
+}
+
+func evalSetArrayIndex(
+	left *object.Array,
+	index object.Object,
+	value object.Object,
+) object.Object {
+	i, ok := index.(*object.Integer)
+	if !ok {
+		return newIndexError(index)
+	}
+	if i.Value >= len(left.Elements) || i.Value < 0 {
+		message := "cannot evaluate program; " +
+			"index %v out of range in index assignment"
+		return object.NewError(message, i.Value)
+	}
+	left.Elements[i.Value] = value
+	return value
+}
+
+func evalSetHashIndex(
+	left *object.Hash,
+	index object.Object,
+	value object.Object,
+) object.Object {
+	i, ok := index.(object.Hashable)
+	if !ok {
+		return newIndexError(index)
+	}
+	left.Pairs[i.HashKey()] = object.HashPair{Key: index, Value: value}
+	return value
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/lexer"
+	"github.com/vincentlabelle/monkey/parser"
+)
+
+func runFormat(args []string, out io.Writer, errOut io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	check := flags.Bool("check", false, "")
+	write := flags.Bool("write", false, "")
+	if err := flags.Parse(args); err != nil {
+		return fail(errOut, "%v", err)
+	}
+	if *check && *write {
+		return fail(errOut, "--check and --write are mutually exclusive")
+	}
+	if flags.NArg() == 0 {
+		return fail(errOut, "missing file to format")
+	}
+	code := exitSuccess
+	for _, path := range flags.Args() {
+		if !formatFile(path, *check, *write, out, errOut) {
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+func formatFile(
+	path string,
+	check bool,
+	write bool,
+	out io.Writer,
+	errOut io.Writer,
+) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(errOut, "monkey: "+err.Error())
+		return false
+	}
+	formatted, ok := formatSource(path, string(content), errOut)
+	if !ok {
+		return false
+	}
+	switch {
+	case check:
+		if formatted != string(content) {
+			fmt.Fprintln(out, path)
+			return false
+		}
+	case write:
+		if formatted != string(content) {
+			return writeFile(path, formatted, errOut)
+		}
+	default:
+		fmt.Fprint(out, formatted)
+	}
+	return true
+}
+
+func formatSource(
+	path string,
+	content string,
+	errOut io.Writer,
+) (string, bool) {
+	lex := lexer.NewFile(path, content)
+	lex.KeepComments()
+	p := parser.New(lex)
+	program, errors := p.ParseProgram()
+	for _, err := range errors {
+		fmt.Fprintln(errOut, "syntax error: "+err.Error())
+	}
+	return getShebang(content) + ast.Format(program), len(errors) == 0
+}
+
+func getShebang(content string) string {
+	if !strings.HasPrefix(content, "#!") {
+		return ""
+	}
+	line, _, _ := strings.Cut(content, "\n")
+	return line + "\n"
+}
+
+func writeFile(path string, content string, errOut io.Writer) bool {
+	info, err := os.Stat(path)
+	if err == nil {
+		err = os.WriteFile(path, []byte(content), info.Mode().Perm())
+	}
+	if err != nil {
+		fmt.Fprintln(errOut, "monkey: "+err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,15 @@
+package lexer
+
+import "github.com/vincentlabelle/monkey/token"
+
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Message
+	}
+	return e.Message
+}
@@ -1,18 +1,82 @@
 package lexer
 
-import "github.com/vincentlabelle/monkey/token"
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vincentlabelle/monkey/token"
+)
 
 type Lexer struct {
 	input    string
+	file     string
 	position int
+	line     int
+	column   int
+	comments bool
+	errors   []*Error
 }
 
 func New(input string) *Lexer {
-	return &Lexer{input: input}
+	return NewFile("", input)
+}
+
+func NewFile(file string, input string) *Lexer {
+	l := &Lexer{input: input, file: file, line: 1, column: 1}
+	l.skipShebang()
+	return l
+}
+
+func (l *Lexer) skipShebang() {
+	if l.getBinaryString() != "#!" {
+		return
+	}
+	for !l.isEOF() && l.getUnaryChar() != '\n' {
+		l.forward(1)
+	}
+}
+
+func (l *Lexer) KeepComments() {
+	l.comments = true
+}
+
+func (l *Lexer) Errors() []*Error {
+	return l.errors
+}
+
+func (l *Lexer) addError(pos token.Position, format string, a ...any) {
+	message := fmt.Sprintf(format, a...)
+	l.errors = append(l.errors, &Error{Pos: pos, Message: message})
 }
 
 func (l *Lexer) NextToken() token.Token {
+	for {
+		tok := l.nextPositionedToken()
+		if tok.Type != token.COMMENT || l.comments {
+			return tok
+		}
+	}
+}
+
+func (l *Lexer) nextPositionedToken() token.Token {
 	l.skipWhite()
+	start := l.currentPosition()
+	tok := l.innerNextToken()
+	tok.Pos, tok.End = start, l.currentPosition()
+	return tok
+}
+
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{
+		File:   l.file,
+		Line:   l.line,
+		Column: l.column,
+		Offset: l.position,
+	}
+}
+
+func (l *Lexer) innerNextToken() token.Token {
 	if l.isEOF() {
 		return token.Token{Type: token.EOF, Literal: ""}
 	}
@@ -39,30 +103,92 @@ func (l *Lexer) getUnaryChar() byte {
 }
 
 func (l *Lexer) forward(times int) {
-	l.position += times
+	for i := 0; i < times && !l.isEOF(); i++ {
+		l.advance(l.getUnaryChar())
+		l.position++
+	}
+}
+
+func (l *Lexer) advance(char byte) {
+	if char == '\n' {
+		l.line++
+		l.column = 1
+	} else if !isContinuationByte(char) {
+		l.column++
+	}
+}
+
+func isContinuationByte(char byte) bool {
+	return char&0xC0 == 0x80
 }
 
 func (l *Lexer) nextToken() token.Token {
 	var tok token.Token
-	if tok_, ok := l.getBinary(); ok {
+	if tok_, ok := l.getComment(); ok {
+		tok = tok_
+	} else if tok_, ok := l.getBinary(); ok {
 		tok = tok_
 		l.forward(2)
 	} else if tok_, ok := l.getUnary(); ok {
 		tok = tok_
 		l.forward(1)
-	} else if tok_, ok := l.getInt(); ok {
+	} else if tok_, ok := l.getNumber(); ok {
 		tok = tok_
 	} else if tok_, ok := l.getLetter(); ok {
 		tok = tok_
 	} else if tok_, ok := l.getQuoted(); ok {
 		tok = tok_
 	} else {
-		tok = token.Token{Type: token.ILLEGAL, Literal: l.getUnaryString()}
-		l.forward(1)
+		_, width := l.getRune()
+		literal := l.input[l.position : l.position+width]
+		tok = token.Token{Type: token.ILLEGAL, Literal: literal}
+		l.addError(l.currentPosition(), "unexpected character %q", literal)
+		l.forward(width)
 	}
 	return tok
 }
 
+func (l *Lexer) getComment() (token.Token, bool) {
+	switch l.getBinaryString() {
+	case "//":
+		return l.getLineComment(), true
+	case "/*":
+		return l.getBlockComment(), true
+	}
+	return token.Token{}, false
+}
+
+func (l *Lexer) getLineComment() token.Token {
+	start := l.position
+	for !l.isEOF() && l.getUnaryChar() != '\n' {
+		l.forward(1)
+	}
+	return token.Token{Type: token.COMMENT, Literal: l.input[start:l.position]}
+}
+
+func (l *Lexer) getBlockComment() token.Token {
+	start, pos := l.position, l.currentPosition()
+	depth := 0
+	for !l.isEOF() {
+		switch l.getBinaryString() {
+		case "/*":
+			depth++
+			l.forward(2)
+		case "*/":
+			depth--
+			l.forward(2)
+		default:
+			l.forward(1)
+		}
+		if depth == 0 {
+			literal := l.input[start:l.position]
+			return token.Token{Type: token.COMMENT, Literal: literal}
+		}
+	}
+	l.addError(pos, "unterminated block comment")
+	return token.Token{Type: token.ILLEGAL, Literal: l.input[start:l.position]}
+}
+
 func (l *Lexer) getBinary() (token.Token, bool) {
 	str := l.getBinaryString()
 	return l.getBinaryToken(str)
@@ -98,35 +224,77 @@ func (l *Lexer) getUnaryToken(char byte) (token.Token, bool) {
 	return token.Token{}, false
 }
 
-func (l *Lexer) getInt() (token.Token, bool) {
+func (l *Lexer) getNumber() (token.Token, bool) {
 	if !l.isDigit() {
 		return token.Token{}, false
 	}
-	return l.getIntToken(), true
+	return l.getNumberToken(), true
 }
 
 func (l *Lexer) isDigit() bool {
-	char := l.getUnaryChar()
+	return l.isDigitAt(l.position)
+}
+
+func (l *Lexer) isDigitAt(position int) bool {
+	if position >= len(l.input) {
+		return false
+	}
+	char := l.input[position]
 	return '0' <= char && char <= '9'
 }
 
-func (l *Lexer) getIntToken() token.Token {
-	literal := l.getIntLiteral()
+func (l *Lexer) getNumberToken() token.Token {
+	start := l.position
+	l.skipDigits()
+	fraction, exponent := l.skipFraction(), l.skipExponent()
+	literal := l.input[start:l.position]
+	if fraction || exponent {
+		return token.Token{Type: token.FLOAT, Literal: literal}
+	}
 	return token.Token{Type: token.INT, Literal: literal}
 }
 
-func (l *Lexer) getIntLiteral() string {
-	literal := ""
+func (l *Lexer) skipDigits() {
 	for !l.isEOF() && l.isDigit() {
-		literal += l.getUnaryString()
 		l.forward(1)
 	}
-	return literal
 }
 
-func (l *Lexer) getUnaryString() string {
-	char := l.getUnaryChar()
-	return string(char)
+func (l *Lexer) skipFraction() bool {
+	if l.getUnaryCharAt(l.position) != '.' || !l.isDigitAt(l.position+1) {
+		return false
+	}
+	l.forward(1)
+	l.skipDigits()
+	return true
+}
+
+func (l *Lexer) skipExponent() bool {
+	char := l.getUnaryCharAt(l.position)
+	if char != 'e' && char != 'E' {
+		return false
+	}
+	width := 1
+	if sign := l.getUnaryCharAt(l.position + 1); sign == '+' || sign == '-' {
+		width++
+	}
+	if !l.isDigitAt(l.position + width) {
+		return false
+	}
+	l.forward(width)
+	l.skipDigits()
+	return true
+}
+
+func (l *Lexer) getUnaryCharAt(position int) byte {
+	if position >= len(l.input) {
+		return 0
+	}
+	return l.input[position]
+}
+
+func (l *Lexer) getRune() (rune, int) {
+	return utf8.DecodeRuneInString(l.input[l.position:])
 }
 
 func (l *Lexer) getLetter() (token.Token, bool) {
@@ -137,10 +305,8 @@ func (l *Lexer) getLetter() (token.Token, bool) {
 }
 
 func (l *Lexer) isLetter() bool {
-	char := l.getUnaryChar()
-	return 'a' <= char && char <= 'z' ||
-		'A' <= char && char <= 'Z' ||
-		char == '_'
+	char, _ := l.getRune()
+	return unicode.IsLetter(char) || char == '_'
 }
 
 func (l *Lexer) getLetterToken() token.Token {
@@ -150,12 +316,12 @@ func (l *Lexer) getLetterToken() token.Token {
 }
 
 func (l *Lexer) getLetterLiteral() string {
-	literal := ""
+	start := l.position
 	for !l.isEOF() && l.isLetter() {
-		literal += l.getUnaryString()
-		l.forward(1)
+		_, width := l.getRune()
+		l.forward(width)
 	}
-	return literal
+	return l.input[start:l.position]
 }
 
 func (l *Lexer) getLetterType(literal string) token.TokenType {
@@ -164,30 +330,3 @@ func (l *Lexer) getLetterType(literal string) token.TokenType {
 	}
 	return token.IDENT
 }
-
-func (l *Lexer) getQuoted() (token.Token, bool) {
-	if !l.isQuote() {
-		return token.Token{}, false
-	}
-	return l.getQuotedToken(), true
-}
-
-func (l *Lexer) isQuote() bool {
-	return l.getUnaryChar() == '"'
-}
-
-func (l *Lexer) getQuotedToken() token.Token {
-	literal := l.getQuotedLiteral()
-	return token.Token{Type: token.STRING, Literal: literal}
-}
-
-func (l *Lexer) getQuotedLiteral() string {
-	l.forward(1)
-	literal := ""
-	for !l.isEOF() && !l.isQuote() {
-		literal += l.getUnaryString()
-		l.forward(1)
-	}
-	l.forward(1)
-	return literal
-}
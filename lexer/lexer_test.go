@@ -12,7 +12,7 @@ func Test(t *testing.T) {
 		expected []token.Token
 	}{
 		{
-			`=  +-*/!<>,;(){}==!=# `,
+			`=  +-*/!<>,;(){}==!=&&||# `,
 			[]token.Token{
 				{Type: token.ASSIGN, Literal: "="},
 				{Type: token.PLUS, Literal: "+"},
@@ -30,10 +30,30 @@ func Test(t *testing.T) {
 				{Type: token.RBRACE, Literal: "}"},
 				{Type: token.EQ, Literal: "=="},
 				{Type: token.NE, Literal: "!="},
+				{Type: token.AND, Literal: "&&"},
+				{Type: token.OR, Literal: "||"},
 				{Type: token.ILLEGAL, Literal: "#"},
 				{Type: token.EOF, Literal: ""},
 			},
 		},
+		{
+			`<= >= % ** & | ^ ~ << >> <<=`,
+			[]token.Token{
+				{Type: token.LE, Literal: "<="},
+				{Type: token.GE, Literal: ">="},
+				{Type: token.PERCENT, Literal: "%"},
+				{Type: token.POWER, Literal: "**"},
+				{Type: token.AMPERSAND, Literal: "&"},
+				{Type: token.PIPE, Literal: "|"},
+				{Type: token.CARET, Literal: "^"},
+				{Type: token.TILDE, Literal: "~"},
+				{Type: token.SHL, Literal: "<<"},
+				{Type: token.SHR, Literal: ">>"},
+				{Type: token.SHL, Literal: "<<"},
+				{Type: token.ASSIGN, Literal: "="},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
 		{
 			`5+ 50 == 55`,
 			[]token.Token{
@@ -54,7 +74,7 @@ func Test(t *testing.T) {
 			};
 
 			let result = add(five, ten);
-			!-/*5;
+			!-/ *5;
 			5 < 10 > 5;
 
 			if (5 < 10) {
@@ -160,14 +180,225 @@ func Test(t *testing.T) {
 				{Type: token.EOF, Literal: ""},
 			},
 		},
+		{
+			`while for in break continue`,
+			[]token.Token{
+				{Type: token.WHILE, Literal: "while"},
+				{Type: token.FOR, Literal: "for"},
+				{Type: token.IN, Literal: "in"},
+				{Type: token.BREAK, Literal: "break"},
+				{Type: token.CONTINUE, Literal: "continue"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			`export let m = import "m"; m.x`,
+			[]token.Token{
+				{Type: token.EXPORT, Literal: "export"},
+				{Type: token.LET, Literal: "let"},
+				{Type: token.IDENT, Literal: "m"},
+				{Type: token.ASSIGN, Literal: "="},
+				{Type: token.IMPORT, Literal: "import"},
+				{Type: token.STRING, Literal: "m"},
+				{Type: token.SEMICOLON, Literal: ";"},
+				{Type: token.IDENT, Literal: "m"},
+				{Type: token.DOT, Literal: "."},
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			`1.5 2e10 3.0E-2 4e+1 7e 8.x`,
+			[]token.Token{
+				{Type: token.FLOAT, Literal: "1.5"},
+				{Type: token.FLOAT, Literal: "2e10"},
+				{Type: token.FLOAT, Literal: "3.0E-2"},
+				{Type: token.FLOAT, Literal: "4e+1"},
+				{Type: token.INT, Literal: "7"},
+				{Type: token.IDENT, Literal: "e"},
+				{Type: token.INT, Literal: "8"},
+				{Type: token.DOT, Literal: "."},
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"\"a\\\"b\\\\c\\nd\\te\\u{e9}\\u{1F600}\" `raw\\n\"\nline`",
+			[]token.Token{
+				{Type: token.STRING, Literal: "a\"b\\c\nd\te\u00e9\U0001F600"},
+				{Type: token.STRING, Literal: "raw\\n\"\nline"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			`let café = "naïve"; _ü;`,
+			[]token.Token{
+				{Type: token.LET, Literal: "let"},
+				{Type: token.IDENT, Literal: "café"},
+				{Type: token.ASSIGN, Literal: "="},
+				{Type: token.STRING, Literal: "naïve"},
+				{Type: token.SEMICOLON, Literal: ";"},
+				{Type: token.IDENT, Literal: "_ü"},
+				{Type: token.SEMICOLON, Literal: ";"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"1 // one\n2 /* two /* nested */ */ 3 /**/ // end",
+			[]token.Token{
+				{Type: token.INT, Literal: "1"},
+				{Type: token.INT, Literal: "2"},
+				{Type: token.INT, Literal: "3"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"1 /* open /* nested */ 2",
+			[]token.Token{
+				{Type: token.INT, Literal: "1"},
+				{Type: token.ILLEGAL, Literal: "/* open /* nested */ 2"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
 	}
 
 	for _, s := range setup {
 		lex := New(s.input)
 		for _, expected := range s.expected {
 			actual := lex.NextToken()
-			if actual != expected {
-				t.Fatalf("expected=%v, actual=%v", expected, actual)
+			testToken(t, actual, expected)
+		}
+	}
+}
+
+func testToken(t *testing.T, actual token.Token, expected token.Token) {
+	if actual.Type != expected.Type || actual.Literal != expected.Literal {
+		t.Fatalf("expected=%v, actual=%v", expected, actual)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	input := "let x = 5;\n  \"é\" + y;"
+	setup := []struct {
+		line   int
+		column int
+		offset int
+		end    int
+	}{
+		{1, 1, 0, 3},
+		{1, 5, 4, 5},
+		{1, 7, 6, 7},
+		{1, 9, 8, 9},
+		{1, 10, 9, 10},
+		{2, 3, 13, 17},
+		{2, 7, 18, 19},
+		{2, 9, 20, 21},
+		{2, 10, 21, 22},
+		{2, 11, 22, 22},
+	}
+
+	lex := NewFile("a.mk", input)
+	for _, s := range setup {
+		expected := token.Position{
+			File:   "a.mk",
+			Line:   s.line,
+			Column: s.column,
+			Offset: s.offset,
+		}
+		actual := lex.NextToken()
+		if actual.Pos != expected {
+			t.Fatalf(
+				"position mismatch. got=%v, expected=%v",
+				actual.Pos,
+				expected,
+			)
+		}
+		if actual.End.Offset != s.end {
+			t.Fatalf(
+				"end offset mismatch. got=%v, expected=%v",
+				actual.End.Offset,
+				s.end,
+			)
+		}
+	}
+}
+
+func TestShebang(t *testing.T) {
+	input := "#!/usr/bin/env monkey run\nlet x;"
+	expected := []token.Token{
+		{Type: token.LET, Literal: "let"},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.SEMICOLON, Literal: ";"},
+		{Type: token.EOF, Literal: ""},
+	}
+
+	lex := New(input)
+	for _, e := range expected {
+		actual := lex.NextToken()
+		testToken(t, actual, e)
+	}
+	if len(lex.Errors()) > 0 {
+		t.Fatalf("unexpected errors. got=%v", lex.Errors())
+	}
+}
+
+func TestKeepComments(t *testing.T) {
+	input := "// head\nlet /* a */ x = 1; // tail"
+	expected := []token.Token{
+		{Type: token.COMMENT, Literal: "// head"},
+		{Type: token.LET, Literal: "let"},
+		{Type: token.COMMENT, Literal: "/* a */"},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.ASSIGN, Literal: "="},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.SEMICOLON, Literal: ";"},
+		{Type: token.COMMENT, Literal: "// tail"},
+		{Type: token.EOF, Literal: ""},
+	}
+
+	lex := New(input)
+	lex.KeepComments()
+	for _, e := range expected {
+		actual := lex.NextToken()
+		testToken(t, actual, e)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected []string
+	}{
+		{"1 + 2", []string{}},
+		{"1 # 2", []string{"1:3: unexpected character \"#\""}},
+		{"1\n /* /* */", []string{"2:2: unterminated block comment"}},
+		{`x = "abc`, []string{"1:5: unterminated string literal"}},
+		{"`abc", []string{"1:1: unterminated raw string literal"}},
+		{`"a\qb"`, []string{"1:3: invalid escape sequence \\q"}},
+		{`"\u{110000}"`, []string{"1:2: invalid unicode code point \"110000\""}},
+		{`"\u00e9"`, []string{"1:2: invalid unicode escape sequence; expected {"}},
+		{"é € x", []string{"1:3: unexpected character \"€\""}},
+	}
+
+	for _, s := range setup {
+		lex := New(s.input)
+		for lex.NextToken().Type != token.EOF {
+		}
+		actual := lex.Errors()
+		if len(actual) != len(s.expected) {
+			t.Fatalf(
+				"number of errors mismatch. got=%v, expected=%v",
+				len(actual),
+				len(s.expected),
+			)
+		}
+		for i, e := range s.expected {
+			if actual[i].Error() != e {
+				t.Fatalf(
+					"error mismatch. got=%q, expected=%q",
+					actual[i].Error(),
+					e,
+				)
 			}
 		}
 	}
@@ -0,0 +1,112 @@
+package lexer
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vincentlabelle/monkey/token"
+)
+
+var escapes = map[byte]string{
+	'"':  "\"",
+	'\\': "\\",
+	'n':  "\n",
+	't':  "\t",
+}
+
+func (l *Lexer) getQuoted() (token.Token, bool) {
+	switch l.getUnaryChar() {
+	case '"':
+		return l.getQuotedToken(), true
+	case '`':
+		return l.getRawToken(), true
+	}
+	return token.Token{}, false
+}
+
+func (l *Lexer) getQuotedToken() token.Token {
+	start, pos := l.position, l.currentPosition()
+	l.forward(1)
+	var literal strings.Builder
+	for !l.isEOF() {
+		switch l.getUnaryChar() {
+		case '"':
+			l.forward(1)
+			return token.Token{Type: token.STRING, Literal: literal.String()}
+		case '\\':
+			literal.WriteString(l.getEscape())
+		default:
+			literal.WriteByte(l.getUnaryChar())
+			l.forward(1)
+		}
+	}
+	l.addError(pos, "unterminated string literal")
+	return token.Token{Type: token.ILLEGAL, Literal: l.input[start:l.position]}
+}
+
+func (l *Lexer) getEscape() string {
+	pos := l.currentPosition()
+	l.forward(1)
+	if l.isEOF() {
+		return ""
+	}
+	char := l.getUnaryChar()
+	if escape, ok := escapes[char]; ok {
+		l.forward(1)
+		return escape
+	}
+	if char == 'u' {
+		return l.getUnicodeEscape(pos)
+	}
+	_, width := l.getRune()
+	literal := l.input[l.position : l.position+width]
+	l.addError(pos, "invalid escape sequence \\%v", literal)
+	l.forward(width)
+	return literal
+}
+
+func (l *Lexer) getUnicodeEscape(pos token.Position) string {
+	l.forward(1)
+	if l.isEOF() || l.getUnaryChar() != '{' {
+		l.addError(pos, "invalid unicode escape sequence; expected {")
+		return ""
+	}
+	l.forward(1)
+	start := l.position
+	for !l.isEOF() && l.getUnaryChar() != '}' && l.getUnaryChar() != '"' {
+		l.forward(1)
+	}
+	digits := l.input[start:l.position]
+	if l.isEOF() || l.getUnaryChar() != '}' {
+		l.addError(pos, "invalid unicode escape sequence; expected }")
+		return ""
+	}
+	l.forward(1)
+	return l.getCodePoint(pos, digits)
+}
+
+func (l *Lexer) getCodePoint(pos token.Position, digits string) string {
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || len(digits) > 6 || !utf8.ValidRune(rune(value)) {
+		l.addError(pos, "invalid unicode code point %q", digits)
+		return ""
+	}
+	return string(rune(value))
+}
+
+func (l *Lexer) getRawToken() token.Token {
+	start, pos := l.position, l.currentPosition()
+	l.forward(1)
+	for !l.isEOF() && l.getUnaryChar() != '`' {
+		l.forward(1)
+	}
+	if l.isEOF() {
+		l.addError(pos, "unterminated raw string literal")
+		literal := l.input[start:l.position]
+		return token.Token{Type: token.ILLEGAL, Literal: literal}
+	}
+	l.forward(1)
+	literal := l.input[start+1 : l.position-1]
+	return token.Token{Type: token.STRING, Literal: literal}
+}
@@ -1,14 +1,8 @@
 package main
 
-import (
-	"fmt"
-	"os"
-
-	"github.com/vincentlabelle/monkey/repl"
-)
+import "os"
 
 func main() {
-	fmt.Println("Hello! This is the Monkey programming language!")
-	fmt.Println("Feel free to type in commands.")
-	repl.Start(os.Stdin, os.Stdout)
+	code := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	os.Exit(code)
 }
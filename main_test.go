@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	setup := []struct {
+		args     []string
+		script   string
+		expected int
+		stderr   string
+	}{
+		{[]string{"run"}, "1 + 1;", exitSuccess, ""},
+		{[]string{"run", "--engine=vm"}, "1 + 1;", exitSuccess, ""},
+		{
+			[]string{"run"},
+			"while (true) { let a = [1, if (true) { continue; }]; }",
+			exitFailure,
+			"script.mk:1:40: continue must not be inside an expression",
+		},
+		{
+			[]string{"run", "--engine=vm"},
+			"while (true) { let a = [1, if (true) { continue; }]; }",
+			exitFailure,
+			"script.mk:1:40: continue must not be inside an expression",
+		},
+		{
+			[]string{"run"},
+			"let f = fn() { f = 1; }; f(); if (f != 1) { 1 + true; }",
+			exitSuccess,
+			"",
+		},
+		{
+			[]string{"run", "--engine=vm"},
+			"let f = fn() { f = 1; }; f(); if (f != 1) { 1 + true; }",
+			exitSuccess,
+			"",
+		},
+		{
+			[]string{"run"},
+			"let f = fn(x, x) { x }; f(1, 2);",
+			exitFailure,
+			"script.mk:1:15: duplicate parameter x",
+		},
+		{
+			[]string{"run", "--engine=vm"},
+			"let f = fn(x, x) { x }; f(1, 2);",
+			exitFailure,
+			"script.mk:1:15: duplicate parameter x",
+		},
+		{
+			[]string{"run"},
+			"#!/usr/bin/env monkey run\nlet x = 1;",
+			exitSuccess,
+			"",
+		},
+		{
+			[]string{"run"},
+			"1 + true;",
+			exitFailure,
+			"script.mk:1:1: cannot evaluate program",
+		},
+		{
+			[]string{"run", "--engine=vm"},
+			"1 + true;",
+			exitFailure,
+			"script.mk:1:1: cannot evaluate program; operands",
+		},
+		{
+			[]string{"run"},
+			"let = 1;",
+			exitFailure,
+			"script.mk:1:5",
+		},
+		{[]string{"run", "--engine=vm", "-O=2"}, "1 + 1;", exitSuccess, ""},
+		{[]string{"run", "--engine=vm", "-O=0"}, "1 + 1;", exitSuccess, ""},
+		{[]string{"run", "-O=3"}, "1;", exitUsage, "unknown optimization level"},
+		{[]string{"run", "--engine=js"}, "1;", exitUsage, "unknown engine"},
+		{[]string{"unknown"}, "", exitUsage, "unknown command"},
+		{[]string{"repl", "extra"}, "", exitUsage, "unexpected argument"},
+	}
+
+	for _, s := range setup {
+		path := filepath.Join(dir, "script.mk")
+		if err := os.WriteFile(path, []byte(s.script), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		args := s.args
+		if args[0] == "run" {
+			args = append(args, path)
+		}
+		testRun(t, args, s.expected, s.stderr)
+	}
+}
+
+func TestRunArguments(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		filepath.Join(dir, "script.mk"): `
+			let lib = import "./lib.mk";
+			if (len(args()) != 2 || lib.count != 2) { 1 + true; }
+		`,
+		filepath.Join(dir, "lib.mk"): `export let count = len(args());`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(dir, "script.mk")
+	for _, engine := range []string{"eval", "vm"} {
+		args := []string{"run", "--engine=" + engine, path, "a", "b"}
+		testRun(t, args, exitSuccess, "")
+	}
+}
+
+func TestRunImport(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	files := map[string]string{
+		filepath.Join(dir, "script.mk"): `
+			let sibling = import "./sibling.mk";
+			let shared = import "shared.mk";
+			if (sibling.x + shared.y != 3) { 1 + true; }
+		`,
+		filepath.Join(dir, "sibling.mk"): `export let x = 1;`,
+		filepath.Join(lib, "shared.mk"):  `export let y = 2;`,
+	}
+	if err := os.Mkdir(lib, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(dir, "script.mk")
+	for _, engine := range []string{"eval", "vm"} {
+		args := []string{"run", "--engine=" + engine, "--path=" + lib, path}
+		testRun(t, args, exitSuccess, "")
+		args = []string{"run", "--engine=" + engine, path}
+		testRun(t, args, exitFailure, `cannot find module "shared.mk"`)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	files := map[string]string{
+		filepath.Join(dir, "script.mk"): `
+			let shared = import "shared.mk";
+			if (len(args()) != 1 || shared.y != 2) { 1 + true; }
+		`,
+		filepath.Join(dir, "broken.mk"): `let f = fn() { 1 + true; }; f();`,
+		filepath.Join(lib, "shared.mk"): `export let y = 2;`,
+	}
+	if err := os.Mkdir(lib, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	script := filepath.Join(dir, "script.mk")
+	compiled := filepath.Join(dir, "script.mkc")
+	args := []string{"compile", "--path=" + lib, "-O=2", script}
+	testRun(t, args, exitSuccess, "")
+	testRun(t, []string{"run", compiled, "a"}, exitSuccess, "")
+	args = []string{"run", "--engine=vm", compiled, "a"}
+	testRun(t, args, exitSuccess, "")
+	args = []string{"run", "--engine=eval", compiled, "a"}
+	testRun(t, args, exitUsage, `engine "eval" cannot run bytecode`)
+	testRun(t, []string{"run", compiled}, exitFailure, "runtime error")
+
+	broken := filepath.Join(dir, "broken.mkc")
+	args = []string{"compile", "-o=" + broken, filepath.Join(dir, "broken.mk")}
+	testRun(t, args, exitSuccess, "")
+	expected := "broken.mk:1:16: cannot evaluate program"
+	testRun(t, []string{"run", broken}, exitFailure, expected)
+	testRun(t, []string{"compile", script}, exitFailure, "compile error")
+	testRun(t, []string{"compile"}, exitUsage, "missing file")
+
+	content, err := os.ReadFile(compiled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content[len(content)/2] ^= 0xff
+	if err := os.WriteFile(compiled, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testRun(t, []string{"run", compiled}, exitFailure, "checksum mismatch")
+	if err := os.WriteFile(compiled, []byte("1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testRun(t, []string{"run", compiled}, exitFailure, "missing magic header")
+}
+
+func TestDisasm(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.mk")
+	if err := os.WriteFile(path, []byte("1 + 2;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<main>:\n" +
+		"    // script.mk:1: 1 + 2;\n" +
+		"    0000 OpConstant 0           ; 3\n" +
+		"    0003 OpPop\n"
+
+	testFormat(t, []string{"disasm", path}, exitSuccess, expected)
+	testRun(t, []string{"compile", path}, exitSuccess, "")
+	compiled := filepath.Join(dir, "script.mkc")
+	testFormat(t, []string{"disasm", compiled}, exitSuccess, expected)
+	testRun(t, []string{"disasm"}, exitUsage, "missing file")
+}
+
+func TestFormat(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.mk")
+	script := "#!/usr/bin/env monkey run\nlet x=1 // one\nputs( x )"
+	expected := "#!/usr/bin/env monkey run\nlet x = 1; // one\nputs(x);\n"
+	if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testFormat(t, []string{"fmt", path}, exitSuccess, expected)
+	testFormat(t, []string{"fmt", "--check", path}, exitFailure, path+"\n")
+	testFormat(t, []string{"fmt", "--write", path}, exitSuccess, "")
+	testFormat(t, []string{"fmt", "--check", path}, exitSuccess, "")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != expected {
+		t.Fatalf(
+			"content mismatch. got=%q, expected=%q",
+			content,
+			expected,
+		)
+	}
+
+	if err := os.WriteFile(path, []byte("let = 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testRun(t, []string{"fmt", "--write", path}, exitFailure, "script.mk:1:5")
+	testRun(t, []string{"fmt"}, exitUsage, "missing file")
+	args := []string{"fmt", "--check", "--write", path}
+	testRun(t, args, exitUsage, "mutually exclusive")
+}
+
+func testFormat(t *testing.T, args []string, expected int, stdout string) {
+	var out, errOut bytes.Buffer
+	actual := run(args, strings.NewReader(""), &out, &errOut)
+	if actual != expected {
+		t.Fatalf(
+			"exit code mismatch. got=%v, expected=%v, stderr=%q",
+			actual,
+			expected,
+			errOut.String(),
+		)
+	}
+	if out.String() != stdout {
+		t.Fatalf("stdout mismatch. got=%q, expected=%q", out.String(), stdout)
+	}
+}
+
+func TestRunMissingFile(t *testing.T) {
+	testRun(t, []string{"run"}, exitUsage, "missing file")
+	testRun(t, []string{"run", "missing.mk"}, exitFailure, "missing.mk")
+}
+
+func testRun(t *testing.T, args []string, expected int, stderr string) {
+	var out, errOut bytes.Buffer
+	actual := run(args, strings.NewReader(""), &out, &errOut)
+	if actual != expected {
+		t.Fatalf(
+			"exit code mismatch. got=%v, expected=%v, stderr=%q",
+			actual,
+			expected,
+			errOut.String(),
+		)
+	}
+	if !strings.Contains(errOut.String(), stderr) {
+		t.Fatalf(
+			"stderr mismatch. got=%q, expected=%q",
+			errOut.String(),
+			stderr,
+		)
+	}
+}
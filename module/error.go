@@ -0,0 +1,16 @@
+package module
+
+import "fmt"
+
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(format string, a ...any) *Error {
+	message := "cannot import; " + fmt.Sprintf(format, a...)
+	return &Error{Message: message}
+}
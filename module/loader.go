@@ -0,0 +1,77 @@
+package module
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/lexer"
+	"github.com/vincentlabelle/monkey/parser"
+)
+
+type Loader struct {
+	fsys  fs.FS
+	paths []string
+}
+
+func NewLoader(fsys fs.FS, paths ...string) *Loader {
+	return &Loader{fsys: fsys, paths: paths}
+}
+
+func (l *Loader) Resolve(from string, name string) (string, error) {
+	for _, candidate := range l.getCandidates(from, name) {
+		if l.exists(candidate) {
+			return candidate, nil
+		}
+	}
+	return "", newError("cannot find module %q", name)
+}
+
+func (l *Loader) getCandidates(from string, name string) []string {
+	if path.IsAbs(name) {
+		return []string{path.Clean(name)}
+	}
+	candidates := []string{}
+	if from != "" {
+		candidates = append(candidates, path.Join(path.Dir(from), name))
+	}
+	if isRelative(name) {
+		return candidates
+	}
+	for _, dir := range l.paths {
+		candidates = append(candidates, path.Join(dir, name))
+	}
+	return candidates
+}
+
+func isRelative(name string) bool {
+	return strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../")
+}
+
+func (l *Loader) exists(name string) bool {
+	info, err := fs.Stat(l.fsys, toFS(name))
+	return err == nil && !info.IsDir()
+}
+
+func toFS(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+func (l *Loader) Load(name string) (*ast.Program, error) {
+	content, err := fs.ReadFile(l.fsys, toFS(name))
+	if err != nil {
+		return nil, newError("cannot read module %q; %v", name, err)
+	}
+	lex := lexer.NewFile(name, string(content))
+	p := parser.New(lex)
+	program, errors := p.ParseProgram()
+	if len(errors) > 0 {
+		return nil, newError("cannot parse module %q; %v", name, errors[0])
+	}
+	return program, nil
+}
@@ -0,0 +1,124 @@
+package module
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func newTestLoader() *Loader {
+	fsys := fstest.MapFS{
+		"app/main.mk":      {Data: []byte(`import "./util.mk";`)},
+		"app/util.mk":      {Data: []byte(`export let x = 1;`)},
+		"app/broken.mk":    {Data: []byte(`let = 1;`)},
+		"lib/math.mk":      {Data: []byte(`export let pi = 3.14;`)},
+		"lib/dir/inner.mk": {Data: []byte(`1;`)},
+	}
+	return NewLoader(fsys, "/lib")
+}
+
+func TestResolve(t *testing.T) {
+	setup := []struct {
+		from     string
+		name     string
+		expected string
+	}{
+		{"/app/main.mk", "./util.mk", "/app/util.mk"},
+		{"/app/main.mk", "util.mk", "/app/util.mk"},
+		{"/app/main.mk", "math.mk", "/lib/math.mk"},
+		{"/app/main.mk", "../lib/math.mk", "/lib/math.mk"},
+		{"/app/main.mk", "/lib/dir/inner.mk", "/lib/dir/inner.mk"},
+		{"", "math.mk", "/lib/math.mk"},
+		{"", "dir/inner.mk", "/lib/dir/inner.mk"},
+	}
+
+	loader := newTestLoader()
+	for _, s := range setup {
+		actual, err := loader.Resolve(s.from, s.name)
+		if err != nil {
+			t.Fatalf("cannot resolve %q; %v", s.name, err)
+		}
+		if actual != s.expected {
+			t.Fatalf(
+				"resolved module mismatch. got=%v, expected=%v",
+				actual,
+				s.expected,
+			)
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	setup := []struct {
+		from     string
+		name     string
+		expected string
+	}{
+		{
+			"/app/main.mk",
+			"./math.mk",
+			`cannot import; cannot find module "./math.mk"`,
+		},
+		{
+			"/app/main.mk",
+			"/lib/dir",
+			`cannot import; cannot find module "/lib/dir"`,
+		},
+		{
+			"",
+			"util.mk",
+			`cannot import; cannot find module "util.mk"`,
+		},
+	}
+
+	loader := newTestLoader()
+	for _, s := range setup {
+		_, err := loader.Resolve(s.from, s.name)
+		if err == nil || err.Error() != s.expected {
+			t.Fatalf("error mismatch. got=%v, expected=%v", err, s.expected)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	loader := newTestLoader()
+	program, err := loader.Load("/app/util.mk")
+	if err != nil {
+		t.Fatalf("cannot load module; %v", err)
+	}
+	if len(program.Statements) != 1 {
+		t.Fatalf(
+			"number of statements mismatch. got=%v, expected=1",
+			len(program.Statements),
+		)
+	}
+	if file := program.Statements[0].Pos().File; file != "/app/util.mk" {
+		t.Fatalf("file mismatch. got=%v, expected=/app/util.mk", file)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	setup := []struct {
+		name     string
+		expected string
+	}{
+		{
+			"/app/missing.mk",
+			`cannot import; cannot read module "/app/missing.mk"; ` +
+				"open app/missing.mk: file does not exist",
+		},
+		{
+			"/app/broken.mk",
+			`cannot import; cannot parse module "/app/broken.mk"; ` +
+				"/app/broken.mk:1:5: let must be followed by an identifier " +
+				"(expected IDENT, got =)",
+		},
+	}
+
+	loader := newTestLoader()
+	for _, s := range setup {
+		_, err := loader.Load(s.name)
+		if err == nil || err.Error() != s.expected {
+			t.Fatalf("error mismatch. got=%v, expected=%v", err, s.expected)
+		}
+	}
+}
@@ -2,7 +2,7 @@ package object
 
 import (
 	"fmt"
-	"log"
+	"unicode/utf8"
 )
 
 var Builtins = []struct {
@@ -15,46 +15,71 @@ var Builtins = []struct {
 	{"last", &Builtin{Fn: last}},
 	{"rest", &Builtin{Fn: rest}},
 	{"push", &Builtin{Fn: push}},
+	{"args", newArguments([]string{})},
+}
+
+func NewBuiltins(arguments []string) []*Builtin {
+	builtins := make([]*Builtin, len(Builtins))
+	for i, b := range Builtins {
+		builtins[i] = b.Builtin
+		if b.Name == "args" {
+			builtins[i] = newArguments(arguments)
+		}
+	}
+	return builtins
+}
+
+func newArguments(values []string) *Builtin {
+	elements := []Object{}
+	for _, value := range values {
+		elements = append(elements, NativeToString(value))
+	}
+	arguments := &Array{Elements: elements}
+	return &Builtin{Fn: func(args ...Object) Object {
+		return args_(arguments, args)
+	}}
 }
 
 func len_(args ...Object) Object {
 	if len(args) != 1 {
-		message := "cannot call built-in; one argument is expected"
-		log.Fatal(message)
+		return NewError("cannot call built-in; one argument is expected")
 	}
-	var obj *Integer
+	var obj Object
 	switch a := args[0].(type) {
 	case *String:
-		obj = &Integer{Value: len(a.Value)}
+		obj = &Integer{Value: utf8.RuneCountInString(a.Value)}
 	case *Array:
 		obj = &Integer{Value: len(a.Elements)}
 	default:
-		message := "cannot call built-in; invalid argument"
-		log.Fatal(message)
+		message := "cannot call built-in; invalid argument %v"
+		obj = NewError(message, TypeOf(a))
 	}
 	return obj
 }
 
 func first(args ...Object) Object {
-	array := getUniqueArray(args)
+	array, err := getUniqueArray(args)
+	if err != nil {
+		return err
+	}
 	return innerFirst(array)
 }
 
-func getUniqueArray(args []Object) *Array {
+func getUniqueArray(args []Object) (*Array, *Error) {
 	if len(args) != 1 {
 		message := "cannot call built-in; one argument is expected"
-		log.Fatal(message)
+		return nil, NewError(message)
 	}
 	return getArray(args[0])
 }
 
-func getArray(arg Object) *Array {
+func getArray(arg Object) (*Array, *Error) {
 	array, ok := arg.(*Array)
 	if !ok {
 		message := "cannot call built-in; argument must be an array"
-		log.Fatal(message)
+		return nil, NewError(message)
 	}
-	return array
+	return array, nil
 }
 
 func innerFirst(array *Array) Object {
@@ -65,7 +90,10 @@ func innerFirst(array *Array) Object {
 }
 
 func last(args ...Object) Object {
-	array := getUniqueArray(args)
+	array, err := getUniqueArray(args)
+	if err != nil {
+		return err
+	}
 	return innerLast(array)
 }
 
@@ -77,7 +105,10 @@ func innerLast(array *Array) Object {
 }
 
 func rest(args ...Object) Object {
-	array := getUniqueArray(args)
+	array, err := getUniqueArray(args)
+	if err != nil {
+		return err
+	}
 	return innerRest(array)
 }
 
@@ -92,10 +123,12 @@ func innerRest(array *Array) Object {
 
 func push(args ...Object) Object {
 	if len(args) != 2 {
-		message := "cannot call built-in; two arguments are expected"
-		log.Fatal(message)
+		return NewError("cannot call built-in; two arguments are expected")
+	}
+	array, err := getArray(args[0])
+	if err != nil {
+		return err
 	}
-	array := getArray(args[0])
 	append_ := args[1]
 	return innerPush(array, append_)
 }
@@ -113,3 +146,12 @@ func puts(args ...Object) Object {
 	}
 	return NULL
 }
+
+func args_(arguments *Array, args []Object) Object {
+	if len(args) != 0 {
+		return NewError("cannot call built-in; no argument is expected")
+	}
+	elements := make([]Object, len(arguments.Elements))
+	copy(elements, arguments.Elements)
+	return &Array{Elements: elements}
+}
@@ -1,8 +1,10 @@
 package object
 
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store    map[string]Object
+	outer    *Environment
+	importer Importer
+	depth    int
 }
 
 func NewEnvironment() *Environment {
@@ -11,11 +13,9 @@ func NewEnvironment() *Environment {
 }
 
 func newBuiltinEnvironment() *Environment {
-	store := map[string]Object{}
-	for _, b := range Builtins {
-		store[b.Name] = b.Builtin
-	}
-	return &Environment{store: store}
+	env := newEnvironment()
+	env.SetArguments([]string{})
+	return env
 }
 
 func NewInnerEnvironment(outer *Environment) *Environment {
@@ -24,10 +24,20 @@ func NewInnerEnvironment(outer *Environment) *Environment {
 	return env
 }
 
+func NewCallEnvironment(outer *Environment, caller *Environment) *Environment {
+	env := NewInnerEnvironment(outer)
+	env.depth = caller.depth + 1
+	return env
+}
+
 func newEnvironment() *Environment {
 	return &Environment{store: map[string]Object{}}
 }
 
+func (e *Environment) Depth() int {
+	return e.depth
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
 	obj, ok := e.store[name]
 	if !ok && e.outer != nil {
@@ -39,3 +49,35 @@ func (e *Environment) Get(name string) (Object, bool) {
 func (e *Environment) Set(name string, obj Object) {
 	e.store[name] = obj
 }
+
+func (e *Environment) Assign(name string, obj Object) bool {
+	if e.outer == nil {
+		return false
+	}
+	if _, ok := e.store[name]; ok {
+		e.store[name] = obj
+		return true
+	}
+	return e.outer.Assign(name, obj)
+}
+
+func (e *Environment) SetArguments(values []string) {
+	if e.outer != nil {
+		e.outer.SetArguments(values)
+		return
+	}
+	for i, b := range NewBuiltins(values) {
+		e.store[Builtins[i].Name] = b
+	}
+}
+
+func (e *Environment) SetImporter(importer Importer) {
+	e.importer = importer
+}
+
+func (e *Environment) Importer() Importer {
+	if e.importer == nil && e.outer != nil {
+		return e.outer.Importer()
+	}
+	return e.importer
+}
@@ -0,0 +1,12 @@
+package object
+
+import "fmt"
+
+func NewError(format string, a ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
+
+func IsError(obj Object) bool {
+	_, ok := obj.(*Error)
+	return ok
+}
@@ -1,20 +1,17 @@
 package object
 
-import "log"
-
 type Hashable interface {
 	Object
 	HashKey() HashKey
 }
 
-func CastToHashable(obj Object) Hashable {
+func CastToHashable(obj Object) (Hashable, *Error) {
 	hash, ok := obj.(Hashable)
 	if !ok {
-		message := "cannot cast to hashable; " +
-			"unexpected object encountered"
-		log.Fatal(message)
+		message := "cannot cast to hashable; unexpected object %v encountered"
+		return nil, NewError(message, TypeOf(obj))
 	}
-	return hash
+	return hash, nil
 }
 
 type HashKey struct {
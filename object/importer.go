@@ -0,0 +1,5 @@
+package object
+
+type Importer interface {
+	Import(from string, name string) Object
+}
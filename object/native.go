@@ -4,6 +4,10 @@ func NativeToInteger(native int) *Integer {
 	return &Integer{Value: native}
 }
 
+func NativeToFloat(native float64) *Float {
+	return &Float{Value: native}
+}
+
 func NativeToBoolean(native bool) *Boolean {
 	if native {
 		return TRUE
@@ -3,10 +3,13 @@ package object
 import (
 	"fmt"
 	"hash/fnv"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/vincentlabelle/monkey/ast"
 	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/token"
 )
 
 type Object interface {
@@ -25,6 +28,26 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: "Integer", Value: uint64(i.Value)}
 }
 
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if strings.ContainsAny(s, ".eIN") {
+		return s
+	}
+	return s + ".0"
+}
+
+func (f *Float) HashKey() HashKey {
+	value := f.Value
+	if value == 0 {
+		value = 0 // Normalize -0 to 0!
+	}
+	return HashKey{Type: "Float", Value: math.Float64bits(value)}
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -100,10 +123,30 @@ func (f *Function) Inspect() string {
 	return "fn(...) {...}"
 }
 
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Inspect() string {
+	return "macro(...) {...}"
+}
+
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Inspect() string {
+	return "quote(" + ast.Format(q.Node) + ")"
+}
+
 type CompiledFunction struct {
+	Name          string
 	Instructions  code.Instructions
 	NumLocals     int
 	NumParameters int
+	SourceMap     code.SourceMap
 }
 
 func (cf *CompiledFunction) Inspect() string {
@@ -119,6 +162,14 @@ func (c *Closure) Inspect() string {
 	return "fn(...) {...}"
 }
 
+type Cell struct {
+	Value Object
+}
+
+func (c *Cell) Inspect() string {
+	return c.Value.Inspect()
+}
+
 type Builtin struct {
 	Fn func(...Object) Object
 }
@@ -127,6 +178,18 @@ func (b *Builtin) Inspect() string {
 	return "builtin function"
 }
 
+type Break struct{}
+
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+type Continue struct{}
+
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
 type ReturnValue struct {
 	Value Object
 }
@@ -134,3 +197,19 @@ type ReturnValue struct {
 func (rv *ReturnValue) Inspect() string {
 	return rv.Value.Inspect()
 }
+
+type Error struct {
+	Message string
+	Pos     token.Position
+}
+
+func (e *Error) Inspect() string {
+	return "error: " + e.Error()
+}
+
+func (e *Error) Error() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Message
+	}
+	return e.Message
+}
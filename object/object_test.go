@@ -1,6 +1,9 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestHashKeyWhenEqual(t *testing.T) {
 	setup := []struct {
@@ -14,6 +17,8 @@ func TestHashKeyWhenEqual(t *testing.T) {
 		{&Integer{Value: 1}, &Integer{Value: 1}},
 		{&Boolean{Value: true}, &Boolean{Value: true}},
 		{&Boolean{Value: false}, &Boolean{Value: false}},
+		{&Float{Value: 1.5}, &Float{Value: 1.5}},
+		{&Float{Value: 0}, &Float{Value: math.Copysign(0, -1)}},
 	}
 
 	for _, s := range setup {
@@ -37,6 +42,8 @@ func TestHashKeyWhenUnequal(t *testing.T) {
 		{&Boolean{Value: true}, &Boolean{Value: false}},
 		{&Boolean{Value: true}, &Integer{Value: 1}},
 		{&Boolean{Value: false}, &Integer{Value: 0}},
+		{&Float{Value: 1}, &Integer{Value: 1}},
+		{&Float{Value: 1.5}, &Float{Value: 2.5}},
 	}
 
 	for _, s := range setup {
@@ -49,3 +56,26 @@ func TestHashKeyWhenUnequal(t *testing.T) {
 		}
 	}
 }
+
+func TestFloatInspect(t *testing.T) {
+	setup := []struct {
+		value    float64
+		expected string
+	}{
+		{1, "1.0"},
+		{-2.5, "-2.5"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+	}
+
+	for _, s := range setup {
+		actual := (&Float{Value: s.value}).Inspect()
+		if actual != s.expected {
+			t.Fatalf(
+				"inspect mismatch. got=%v, expected=%v",
+				actual,
+				s.expected,
+			)
+		}
+	}
+}
@@ -6,3 +6,14 @@ func IsType(obj Object, name string) bool {
 	type_ := reflect.TypeOf(obj)
 	return type_.Kind() == reflect.Pointer && type_.Elem().Name() == name
 }
+
+func TypeOf(obj Object) string {
+	type_ := reflect.TypeOf(obj)
+	if type_ == nil {
+		return "nil"
+	}
+	if type_.Kind() == reflect.Pointer {
+		return type_.Elem().Name()
+	}
+	return type_.Name()
+}
@@ -1,7 +1,9 @@
 package object
 
 var (
-	TRUE  = &Boolean{Value: true}
-	FALSE = &Boolean{Value: false}
-	NULL  = &Null{}
+	TRUE     = &Boolean{Value: true}
+	FALSE    = &Boolean{Value: false}
+	NULL     = &Null{}
+	BREAK    = &Break{}
+	CONTINUE = &Continue{}
 )
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vincentlabelle/monkey/lexer"
+	"github.com/vincentlabelle/monkey/token"
+)
+
+type ParseError struct {
+	Pos      token.Position
+	Expected []token.TokenType
+	Actual   token.TokenType
+	Message  string
+}
+
+func newLexerError(err *lexer.Error) *ParseError {
+	return &ParseError{
+		Pos:     err.Pos,
+		Actual:  token.ILLEGAL,
+		Message: err.Message,
+	}
+}
+
+func (e *ParseError) Error() string {
+	s := e.Message
+	if len(e.Expected) > 0 {
+		s += fmt.Sprintf(
+			" (expected %v, got %v)",
+			castTypes(e.Expected),
+			e.Actual,
+		)
+	}
+	if e.Pos.IsValid() {
+		s = e.Pos.String() + ": " + s
+	}
+	return s
+}
+
+func castTypes(types []token.TokenType) string {
+	s := []string{}
+	for _, type_ := range types {
+		s = append(s, string(type_))
+	}
+	return strings.Join(s, " or ")
+}
+
+type bailout struct{}
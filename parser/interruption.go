@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/token"
+)
+
+func (p *Parser) checkInterruptions(node ast.Node, operand bool) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BreakStatement:
+			p.failInterruption(n, "break", operand)
+		case *ast.ContinueStatement:
+			p.failInterruption(n, "continue", operand)
+		case *ast.ExpressionStatement:
+			p.checkExpressionStatement(n, operand)
+			return false
+		case *ast.WhileStatement:
+			p.checkInterruptions(n.Condition, true)
+			p.checkInterruptions(n.Body, false)
+			return false
+		case *ast.ForStatement:
+			p.checkInterruptions(n.Iterable, true)
+			p.checkInterruptions(n.Body, false)
+			return false
+		case *ast.FunctionLiteral:
+			p.checkInterruptions(n.Body, false)
+			return false
+		case *ast.MacroLiteral:
+			p.checkInterruptions(n.Body, false)
+			return false
+		case ast.Expression:
+			if !operand {
+				p.checkInterruptions(n, true)
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (p *Parser) checkExpressionStatement(
+	statement *ast.ExpressionStatement,
+	operand bool,
+) {
+	expression, ok := statement.Expression.(*ast.IfExpression)
+	if !ok {
+		p.checkInterruptions(statement.Expression, true)
+		return
+	}
+	p.checkInterruptions(expression.Condition, true)
+	p.checkInterruptions(expression.Consequence, operand)
+	if expression.Alternative != nil {
+		p.checkInterruptions(expression.Alternative, operand)
+	}
+}
+
+func (p *Parser) failInterruption(
+	node ast.Node,
+	keyword string,
+	operand bool,
+) {
+	if !operand {
+		return
+	}
+	p.errors = append(p.errors, &ParseError{
+		Pos:     node.Pos(),
+		Actual:  token.Keywords[keyword],
+		Message: keyword + " must not be inside an expression",
+	})
+}
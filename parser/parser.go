@@ -1,7 +1,8 @@
 package parser
 
 import (
-	"log"
+	"fmt"
+	"slices"
 	"strconv"
 
 	"github.com/vincentlabelle/monkey/ast"
@@ -13,6 +14,10 @@ type Parser struct {
 	lex       *lexer.Lexer
 	curToken  token.Token
 	peekToken token.Token
+	depth     int
+	loops     int
+	comments  []*ast.Comment
+	errors    []*ParseError
 }
 
 func New(lex *lexer.Lexer) *Parser {
@@ -24,17 +29,128 @@ func New(lex *lexer.Lexer) *Parser {
 
 func (p *Parser) forward() {
 	p.curToken = p.peekToken
-	p.peekToken = p.lex.NextToken()
+	p.peekToken = p.nextToken()
+	p.updateDepth()
 }
 
-func (p *Parser) ParseProgram() *ast.Program {
+func (p *Parser) nextToken() token.Token {
+	tok := p.lex.NextToken()
+	for tok.Type == token.COMMENT || tok.Type == token.ILLEGAL {
+		if tok.Type == token.COMMENT {
+			p.addComment(tok)
+		}
+		tok = p.lex.NextToken()
+	}
+	return tok
+}
+
+func (p *Parser) addComment(tok token.Token) {
+	span := ast.Span{From: tok.Pos, To: tok.End}
+	p.comments = append(p.comments, &ast.Comment{Span: span, Text: tok.Literal})
+}
+
+func (p *Parser) updateDepth() {
+	if p.isCurToken(token.LBRACE) {
+		p.depth++
+	} else if p.isCurToken(token.RBRACE) && p.depth > 0 {
+		p.depth--
+	}
+}
+
+func (p *Parser) ParseProgram() (*ast.Program, []*ParseError) {
+	start := p.curToken.Pos
 	statements := []ast.Statement{}
 	for !p.isCurToken(token.EOF) {
-		statement := p.parseStatement()
-		statements = append(statements, statement)
+		if statement, ok := p.parseTopStatement(); ok {
+			statements = append(statements, statement)
+		}
+		p.forward()
+	}
+	program := &ast.Program{
+		Span:       p.span(start),
+		Statements: statements,
+		Comments:   p.comments,
+	}
+	p.checkInterruptions(program, false)
+	return program, p.collectErrors()
+}
+
+func (p *Parser) parseTopStatement() (statement ast.Statement, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, isBailout := r.(bailout); !isBailout {
+				panic(r)
+			}
+			p.synchronize()
+			statement, ok = nil, false
+		}
+	}()
+	if p.isCurToken(token.EXPORT) {
+		return p.parseExportStatement(), true
+	}
+	return p.parseStatement(), true
+}
+
+func (p *Parser) parseExportStatement() *ast.LetStatement {
+	start := p.curToken.Pos
+	p.forward()
+	p.expectCur(token.LET, "export must be followed by let")
+	statement := p.parseLetStatement()
+	statement.Span = p.span(start)
+	statement.Exported = true
+	return statement
+}
+
+func (p *Parser) synchronize() {
+	for !p.isCurToken(token.EOF) && !p.isSynchronized() {
 		p.forward()
 	}
-	return &ast.Program{Statements: statements}
+}
+
+func (p *Parser) isSynchronized() bool {
+	if p.depth > 0 {
+		return false
+	}
+	return p.isCurToken(token.SEMICOLON) ||
+		p.isCurToken(token.RBRACE) && !p.isPeekToken(token.SEMICOLON) ||
+		p.isPeekToken(token.LET) ||
+		p.isPeekToken(token.EXPORT) ||
+		p.isPeekToken(token.RETURN) ||
+		p.isPeekToken(token.WHILE) ||
+		p.isPeekToken(token.FOR)
+}
+
+func (p *Parser) collectErrors() []*ParseError {
+	errors := []*ParseError{}
+	for _, err := range p.lex.Errors() {
+		errors = append(errors, newLexerError(err))
+	}
+	errors = append(errors, p.errors...)
+	slices.SortStableFunc(errors, func(x, y *ParseError) int {
+		return x.Pos.Offset - y.Pos.Offset
+	})
+	return errors
+}
+
+func (p *Parser) fail(message string, expected ...token.TokenType) {
+	err := &ParseError{
+		Pos:      p.curToken.Pos,
+		Expected: expected,
+		Actual:   p.curToken.Type,
+		Message:  message,
+	}
+	p.errors = append(p.errors, err)
+	panic(bailout{})
+}
+
+func (p *Parser) expectCur(type_ token.TokenType, message string) {
+	if !p.isCurToken(type_) {
+		p.fail(message, type_)
+	}
+}
+
+func (p *Parser) span(start token.Position) ast.Span {
+	return ast.Span{From: start, To: p.curToken.End}
 }
 
 func (p *Parser) isCurToken(type_ token.TokenType) bool {
@@ -48,29 +164,112 @@ func (p *Parser) parseStatement() ast.Statement {
 	if p.isCurToken(token.RETURN) {
 		return p.parseReturnStatement()
 	}
+	if p.isCurToken(token.WHILE) {
+		return p.parseWhileStatement()
+	}
+	if p.isCurToken(token.FOR) {
+		return p.parseForStatement()
+	}
+	if p.isCurToken(token.BREAK) {
+		return p.parseBreakStatement()
+	}
+	if p.isCurToken(token.CONTINUE) {
+		return p.parseContinueStatement()
+	}
+	if p.isCurToken(token.EXPORT) {
+		p.fail("export must be at top level")
+	}
 	return p.parseExpressionStatement()
 }
 
-func (p *Parser) parseLetStatement() *ast.LetStatement {
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	start := p.curToken.Pos
+	p.forward()
+	p.expectCur(token.LPAREN, "missing ( after while")
+	condition := p.parseExpression(ast.LOWEST)
 	p.forward()
-	if !p.isCurToken(token.IDENT) {
-		message := "cannot parse program; let must be followed by an identifier"
-		log.Fatal(message)
+	p.expectCur(token.LBRACE, "missing { after while")
+	body := p.parseLoopBody()
+	return &ast.WhileStatement{
+		Span:      p.span(start),
+		Condition: condition,
+		Body:      body,
 	}
-	name := p.parseIdentifier()
+}
+
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loops++
+	defer func() { p.loops-- }()
+	body := p.parseBlockStatement()
+	if p.isPeekToken(token.SEMICOLON) {
+		p.forward()
+	}
+	return body
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	start := p.curToken.Pos
+	p.forward()
+	p.expectCur(token.LPAREN, "missing ( after for")
+	p.forward()
+	p.expectCur(token.IDENT, "for must be followed by an identifier")
+	variable := p.parseIdentifier()
 	p.forward()
-	if !p.isCurToken(token.ASSIGN) {
-		message := "cannot parse program; " +
-			"identifier in let statement must be followed by assignement"
-		log.Fatal(message)
+	p.expectCur(token.IN, "missing in after identifier in for")
+	p.forward()
+	iterable := p.parseExpression(ast.LOWEST)
+	p.forward()
+	p.expectCur(token.RPAREN, "missing ) after for")
+	p.forward()
+	p.expectCur(token.LBRACE, "missing { after for")
+	body := p.parseLoopBody()
+	return &ast.ForStatement{
+		Span:     p.span(start),
+		Variable: variable,
+		Iterable: iterable,
+		Body:     body,
+	}
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	if p.loops == 0 {
+		p.fail("break must be inside a loop")
+	}
+	span := p.span(p.curToken.Pos)
+	if p.isPeekToken(token.SEMICOLON) {
+		p.forward()
+	}
+	return &ast.BreakStatement{Span: span}
+}
+
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	if p.loops == 0 {
+		p.fail("continue must be inside a loop")
 	}
+	span := p.span(p.curToken.Pos)
+	if p.isPeekToken(token.SEMICOLON) {
+		p.forward()
+	}
+	return &ast.ContinueStatement{Span: span}
+}
+
+func (p *Parser) parseLetStatement() *ast.LetStatement {
+	start := p.curToken.Pos
+	p.forward()
+	p.expectCur(token.IDENT, "let must be followed by an identifier")
+	name := p.parseIdentifier()
+	p.forward()
+	p.expectCur(
+		token.ASSIGN,
+		"identifier in let statement must be followed by assignment",
+	)
 	p.forward()
-	value := p.parseExpression(LOWEST)
+	value := p.parseExpression(ast.LOWEST)
 	p.setNameOnValue(name, value)
 	if p.isPeekToken(token.SEMICOLON) {
 		p.forward()
 	}
-	return &ast.LetStatement{Name: name, Value: value}
+	return &ast.LetStatement{Span: p.span(start), Name: name, Value: value}
 }
 
 func (p *Parser) setNameOnValue(name *ast.Identifier, value ast.Expression) {
@@ -80,7 +279,10 @@ func (p *Parser) setNameOnValue(name *ast.Identifier, value ast.Expression) {
 }
 
 func (p *Parser) parseIdentifier() *ast.Identifier {
-	return &ast.Identifier{Value: p.curToken.Literal}
+	return &ast.Identifier{
+		Span:  p.span(p.curToken.Pos),
+		Value: p.curToken.Literal,
+	}
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
@@ -98,11 +300,15 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 		expression = p.parseIdentifier()
 	} else if p.isCurToken(token.INT) {
 		expression = p.parseIntegerLiteral()
+	} else if p.isCurToken(token.FLOAT) {
+		expression = p.parseFloatLiteral()
 	} else if p.isCurToken(token.TRUE) || p.isCurToken(token.FALSE) {
 		expression = p.parseBooleanLiteral()
 	} else if p.isCurToken(token.STRING) {
 		expression = p.parseStringLiteral()
-	} else if p.isCurToken(token.BANG) || p.isCurToken(token.MINUS) {
+	} else if p.isCurToken(token.BANG) ||
+		p.isCurToken(token.MINUS) ||
+		p.isCurToken(token.TILDE) {
 		expression = p.parsePrefix()
 	} else if p.isCurToken(token.LPAREN) {
 		expression = p.parseGroupedExpression()
@@ -110,13 +316,20 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 		expression = p.parseIfExpression()
 	} else if p.isCurToken(token.FUNCTION) {
 		expression = p.parseFunctionLiteral()
+	} else if p.isCurToken(token.MACRO) {
+		expression = p.parseMacroLiteral()
+	} else if p.isCurToken(token.IMPORT) {
+		expression = p.parseImportExpression()
 	} else if p.isCurToken(token.LBRACKET) {
 		expression = p.parseArrayLiteral()
 	} else if p.isCurToken(token.LBRACE) {
 		expression = p.parseHashLiteral()
 	} else {
-		message := "cannot parse program; cannot parse prefix expression for %v"
-		log.Fatalf(message, p.curToken.Type)
+		message := fmt.Sprintf(
+			"cannot parse prefix expression for %v",
+			p.curToken.Type,
+		)
+		p.fail(message)
 	}
 	return expression
 }
@@ -124,42 +337,59 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 func (p *Parser) parseIntegerLiteral() *ast.IntegerLiteral {
 	value, err := strconv.Atoi(p.curToken.Literal)
 	if err != nil {
-		message := "cannot parse program; unable to convert ASCII to integer"
-		log.Fatal(message)
+		p.fail("unable to convert ASCII to integer")
+	}
+	return &ast.IntegerLiteral{Span: p.span(p.curToken.Pos), Value: value}
+}
+
+func (p *Parser) parseFloatLiteral() *ast.FloatLiteral {
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.fail("unable to convert ASCII to float")
 	}
-	return &ast.IntegerLiteral{Value: value}
+	return &ast.FloatLiteral{Span: p.span(p.curToken.Pos), Value: value}
 }
 
 func (p *Parser) parseBooleanLiteral() *ast.BooleanLiteral {
-	return &ast.BooleanLiteral{Value: p.isCurToken(token.TRUE)}
+	return &ast.BooleanLiteral{
+		Span:  p.span(p.curToken.Pos),
+		Value: p.isCurToken(token.TRUE),
+	}
 }
 
 func (p *Parser) parseStringLiteral() *ast.StringLiteral {
-	return &ast.StringLiteral{Value: p.curToken.Literal}
+	return &ast.StringLiteral{
+		Span:  p.span(p.curToken.Pos),
+		Value: p.curToken.Literal,
+	}
 }
 
 func (p *Parser) parsePrefix() *ast.PrefixExpression {
+	start := p.curToken.Pos
 	operator := p.curToken.Literal
 	p.forward()
-	right := p.parseExpression(PREFIX)
-	return &ast.PrefixExpression{Operator: operator, Right: right}
+	right := p.parseExpression(ast.PREFIX)
+	return &ast.PrefixExpression{
+		Span:     p.span(start),
+		Operator: operator,
+		Right:    right,
+	}
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.forward()
-	expression := p.parseExpression(LOWEST)
+	expression := p.parseExpression(ast.LOWEST)
 	p.forward()
-	if !p.isCurToken(token.RPAREN) {
-		message := "cannot parse program; missing ) to close grouped expression"
-		log.Fatal(message)
-	}
+	p.expectCur(token.RPAREN, "missing ) to close grouped expression")
 	return expression
 }
 
 func (p *Parser) parseIfExpression() *ast.IfExpression {
+	start := p.curToken.Pos
 	condition, consequence := p.parseIf()
 	alternative := p.parseElse()
 	return &ast.IfExpression{
+		Span:        p.span(start),
 		Condition:   condition,
 		Consequence: consequence,
 		Alternative: alternative,
@@ -168,21 +398,16 @@ func (p *Parser) parseIfExpression() *ast.IfExpression {
 
 func (p *Parser) parseIf() (ast.Expression, *ast.BlockStatement) {
 	p.forward()
-	if !p.isCurToken(token.LPAREN) {
-		message := "cannot parse program; missing ( after if"
-		log.Fatal(message)
-	}
-	condition := p.parseExpression(LOWEST)
+	p.expectCur(token.LPAREN, "missing ( after if")
+	condition := p.parseExpression(ast.LOWEST)
 	p.forward()
-	if !p.isCurToken(token.LBRACE) {
-		message := "cannot parse program; missing { after if"
-		log.Fatal(message)
-	}
+	p.expectCur(token.LBRACE, "missing { after if")
 	consequence := p.parseBlockStatement()
 	return condition, consequence
 }
 
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	start := p.curToken.Pos
 	p.forward()
 	statements := []ast.Statement{}
 	for !p.isCurToken(token.RBRACE) && !p.isCurToken(token.EOF) {
@@ -190,7 +415,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		statements = append(statements, statement)
 		p.forward()
 	}
-	return &ast.BlockStatement{Statements: statements}
+	return &ast.BlockStatement{Span: p.span(start), Statements: statements}
 }
 
 func (p *Parser) parseElse() *ast.BlockStatement {
@@ -198,10 +423,7 @@ func (p *Parser) parseElse() *ast.BlockStatement {
 	if p.isPeekToken(token.ELSE) {
 		p.forward()
 		p.forward()
-		if !p.isCurToken(token.LBRACE) {
-			message := "cannot parse program; missing { after else"
-			log.Fatal(message)
-		}
+		p.expectCur(token.LBRACE, "missing { after else")
 		block = p.parseBlockStatement()
 	}
 	return block
@@ -212,19 +434,50 @@ func (p *Parser) isPeekToken(type_ token.TokenType) bool {
 }
 
 func (p *Parser) parseFunctionLiteral() *ast.FunctionLiteral {
+	start := p.curToken.Pos
 	p.forward()
-	if !p.isCurToken(token.LPAREN) {
-		message := "cannot parse program; missing ( after fn"
-		log.Fatal(message)
+	p.expectCur(token.LPAREN, "missing ( after fn")
+	parameters := p.parseFunctionParameters()
+	p.forward()
+	p.expectCur(token.LBRACE, "missing { after fn")
+	body := p.parseFunctionBody()
+	return &ast.FunctionLiteral{
+		Span:       p.span(start),
+		Parameters: parameters,
+		Body:       body,
 	}
+}
+
+func (p *Parser) parseMacroLiteral() *ast.MacroLiteral {
+	start := p.curToken.Pos
+	p.forward()
+	p.expectCur(token.LPAREN, "missing ( after macro")
 	parameters := p.parseFunctionParameters()
 	p.forward()
-	if !p.isCurToken(token.LBRACE) {
-		message := "cannot parse program; missing { after fn"
-		log.Fatal(message)
+	p.expectCur(token.LBRACE, "missing { after macro")
+	body := p.parseFunctionBody()
+	return &ast.MacroLiteral{
+		Span:       p.span(start),
+		Parameters: parameters,
+		Body:       body,
 	}
-	body := p.parseBlockStatement()
-	return &ast.FunctionLiteral{Parameters: parameters, Body: body}
+}
+
+func (p *Parser) parseImportExpression() *ast.ImportExpression {
+	start := p.curToken.Pos
+	p.forward()
+	p.expectCur(token.STRING, "import must be followed by a string")
+	return &ast.ImportExpression{
+		Span: p.span(start),
+		Path: p.curToken.Literal,
+	}
+}
+
+func (p *Parser) parseFunctionBody() *ast.BlockStatement {
+	loops := p.loops
+	p.loops = 0
+	defer func() { p.loops = loops }()
+	return p.parseBlockStatement()
 }
 
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
@@ -242,28 +495,34 @@ func (p *Parser) innerParseFunctionParameters() []*ast.Identifier {
 	for p.isCurToken(token.COMMA) {
 		p.forward()
 		identifier := p.parseFunctionParameter()
+		p.checkDuplicateParameter(identifiers, identifier)
 		identifiers = append(identifiers, identifier)
 		p.forward()
 	}
-	if !p.isCurToken(token.RPAREN) {
-		message := "cannot parse program; missing ) after fn"
-		log.Fatal(message)
-	}
+	p.expectCur(token.RPAREN, "missing ) after fn")
 	return identifiers
 }
 
 func (p *Parser) parseFunctionParameter() *ast.Identifier {
-	if !p.isCurToken(token.IDENT) {
-		message := "cannot parse program; " +
-			"unexpected token in function parameters"
-		log.Fatal(message)
-	}
+	p.expectCur(token.IDENT, "unexpected token in function parameters")
 	return p.parseIdentifier()
 }
 
+func (p *Parser) checkDuplicateParameter(
+	identifiers []*ast.Identifier,
+	identifier *ast.Identifier,
+) {
+	for _, previous := range identifiers {
+		if previous.Value == identifier.Value {
+			p.fail("duplicate parameter " + identifier.Value)
+		}
+	}
+}
+
 func (p *Parser) parseArrayLiteral() *ast.ArrayLiteral {
+	start := p.curToken.Pos
 	elements := p.parseExpressionList(token.RBRACKET)
-	return &ast.ArrayLiteral{Elements: elements}
+	return &ast.ArrayLiteral{Span: p.span(start), Elements: elements}
 }
 
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
@@ -278,24 +537,22 @@ func (p *Parser) innerParseExpressionList(
 	end token.TokenType,
 ) []ast.Expression {
 	p.forward()
-	expressions := []ast.Expression{p.parseExpression(LOWEST)}
+	expressions := []ast.Expression{p.parseExpression(ast.LOWEST)}
 	p.forward()
 	for p.isCurToken(token.COMMA) {
 		p.forward()
-		expression := p.parseExpression(LOWEST)
+		expression := p.parseExpression(ast.LOWEST)
 		expressions = append(expressions, expression)
 		p.forward()
 	}
-	if !p.isCurToken(end) {
-		message := "cannot parse program; missing %v in expression list"
-		log.Fatalf(message, end)
-	}
+	p.expectCur(end, fmt.Sprintf("missing %v in expression list", end))
 	return expressions
 }
 
 func (p *Parser) parseHashLiteral() *ast.HashLiteral {
+	start := p.curToken.Pos
 	pairs := p.parseHashPairs()
-	return &ast.HashLiteral{Pairs: pairs}
+	return &ast.HashLiteral{Span: p.span(start), Pairs: pairs}
 }
 
 func (p *Parser) parseHashPairs() map[ast.HashKey]ast.Expression {
@@ -316,55 +573,39 @@ func (p *Parser) innerParseHashPairs() map[ast.HashKey]ast.Expression {
 		p.parseHashPair(pairs)
 		p.forward()
 	}
-	if !p.isCurToken(token.RBRACE) {
-		message := "cannot parse program; missing } in hash literal"
-		log.Fatal(message)
-	}
+	p.expectCur(token.RBRACE, "missing } in hash literal")
 	return pairs
 }
 
 func (p *Parser) parseHashPair(pairs map[ast.HashKey]ast.Expression) {
 	key := p.parseHashKey(len(pairs))
 	p.forward()
-	if !p.isCurToken(token.COLON) {
-		message := "cannot parse program; missing : in hash literal"
-		log.Fatal(message)
-	}
+	p.expectCur(token.COLON, "missing : in hash literal")
 	p.forward()
-	pairs[key] = p.parseExpression(LOWEST)
+	pairs[key] = p.parseExpression(ast.LOWEST)
 }
 
 func (p *Parser) parseHashKey(index int) ast.HashKey {
-	expression := p.parseExpression(LOWEST)
+	expression := p.parseExpression(ast.LOWEST)
 	return ast.HashKey{Index: index, Expression: expression}
 }
 
 func (p *Parser) peekPrecedence() int {
-	return p.precedence(p.peekToken.Type)
-}
-
-func (p *Parser) precedence(type_ token.TokenType) int {
-	if precedence, ok := precedences[type_]; ok {
-		return precedence
-	}
-	return LOWEST
+	return ast.Precedence(p.peekToken.Type)
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	var expression ast.Expression
-	if p.isCurToken(token.PLUS) ||
-		p.isCurToken(token.MINUS) ||
-		p.isCurToken(token.ASTERISK) ||
-		p.isCurToken(token.SLASH) ||
-		p.isCurToken(token.EQ) ||
-		p.isCurToken(token.NE) ||
-		p.isCurToken(token.LT) ||
-		p.isCurToken(token.GT) {
+	if slices.Contains(infixOperators, p.curToken.Type) {
 		expression = p.parseInfix(left)
+	} else if p.isCurToken(token.ASSIGN) {
+		return p.parseAssignExpression(left)
 	} else if p.isCurToken(token.LPAREN) {
 		return p.parseCallExpression(left)
 	} else if p.isCurToken(token.LBRACKET) {
 		return p.parseIndexExpression(left)
+	} else if p.isCurToken(token.DOT) {
+		return p.parseMemberExpression(left)
 	} else {
 		expression = left
 	}
@@ -374,48 +615,106 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 func (p *Parser) parseInfix(left ast.Expression) *ast.InfixExpression {
 	operator := p.curToken.Literal
 	precedence := p.curPrecedence()
+	if ast.IsRightAssociative(p.curToken.Type) {
+		precedence--
+	}
 	p.forward()
 	right := p.parseExpression(precedence)
-	return &ast.InfixExpression{Left: left, Operator: operator, Right: right}
+	return &ast.InfixExpression{
+		Span:     p.span(left.Pos()),
+		Left:     left,
+		Operator: operator,
+		Right:    right,
+	}
+}
+
+func (p *Parser) parseAssignExpression(
+	left ast.Expression,
+) *ast.AssignExpression {
+	if !isAssignable(left) {
+		p.fail("invalid assignment target")
+	}
+	p.forward()
+	value := p.parseExpression(ast.ASSIGN - 1)
+	return &ast.AssignExpression{
+		Span:   p.span(left.Pos()),
+		Target: left,
+		Value:  value,
+	}
+}
+
+func isAssignable(expression ast.Expression) bool {
+	switch expression.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+		return true
+	default:
+		return false
+	}
 }
 
 func (p *Parser) curPrecedence() int {
-	return p.precedence(p.curToken.Type)
+	return ast.Precedence(p.curToken.Type)
 }
 
 func (p *Parser) parseCallExpression(
 	left ast.Expression,
 ) *ast.CallExpression {
 	arguments := p.parseExpressionList(token.RPAREN)
-	return &ast.CallExpression{Function: left, Arguments: arguments}
+	return &ast.CallExpression{
+		Span:      p.span(left.Pos()),
+		Function:  left,
+		Arguments: arguments,
+	}
 }
 
 func (p *Parser) parseIndexExpression(
 	left ast.Expression,
 ) *ast.IndexExpression {
 	p.forward()
-	index := p.parseExpression(LOWEST)
+	index := p.parseExpression(ast.LOWEST)
 	p.forward()
-	if !p.isCurToken(token.RBRACKET) {
-		message := "cannot parse program; missing ] in index expression"
-		log.Fatal(message)
+	p.expectCur(token.RBRACKET, "missing ] in index expression")
+	return &ast.IndexExpression{
+		Span:  p.span(left.Pos()),
+		Left:  left,
+		Index: index,
+	}
+}
+
+func (p *Parser) parseMemberExpression(
+	left ast.Expression,
+) *ast.IndexExpression {
+	p.forward()
+	p.expectCur(token.IDENT, "missing identifier after .")
+	index := &ast.StringLiteral{
+		Span:  p.span(p.curToken.Pos),
+		Value: p.curToken.Literal,
+	}
+	return &ast.IndexExpression{
+		Span:  p.span(left.Pos()),
+		Left:  left,
+		Index: index,
 	}
-	return &ast.IndexExpression{Left: left, Index: index}
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+	start := p.curToken.Pos
 	p.forward()
-	value := p.parseExpression(LOWEST)
+	value := p.parseExpression(ast.LOWEST)
 	if p.isPeekToken(token.SEMICOLON) {
 		p.forward()
 	}
-	return &ast.ReturnStatement{Value: value}
+	return &ast.ReturnStatement{Span: p.span(start), Value: value}
 }
 
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
-	expression := p.parseExpression(LOWEST)
+	start := p.curToken.Pos
+	expression := p.parseExpression(ast.LOWEST)
 	if p.isPeekToken(token.SEMICOLON) {
 		p.forward()
 	}
-	return &ast.ExpressionStatement{Expression: expression}
+	return &ast.ExpressionStatement{
+		Span:       p.span(start),
+		Expression: expression,
+	}
 }
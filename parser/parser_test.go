@@ -2,6 +2,7 @@ package parser
 
 import (
 	"maps"
+	"reflect"
 	"testing"
 
 	"github.com/vincentlabelle/monkey/ast"
@@ -23,6 +24,16 @@ func Test(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: `2.5e3;`,
+			expected: &ast.Program{
+				Statements: []ast.Statement{
+					&ast.ExpressionStatement{
+						Expression: &ast.FloatLiteral{Value: 2500},
+					},
+				},
+			},
+		},
 		{
 			input: `foobar;`,
 			expected: &ast.Program{
@@ -194,6 +205,205 @@ func Test(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: `macro(x, y) { x + y; };`,
+			expected: &ast.Program{
+				Statements: []ast.Statement{
+					&ast.ExpressionStatement{
+						Expression: &ast.MacroLiteral{
+							Parameters: []*ast.Identifier{
+								{Value: "x"},
+								{Value: "y"},
+							},
+							Body: &ast.BlockStatement{
+								Statements: []ast.Statement{
+									&ast.ExpressionStatement{
+										Expression: &ast.InfixExpression{
+											Left:     &ast.Identifier{Value: "x"},
+											Operator: "+",
+											Right:    &ast.Identifier{Value: "y"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			input: `-2 ** 3 ** 2;`,
+			expected: &ast.Program{
+				Statements: []ast.Statement{
+					&ast.ExpressionStatement{
+						Expression: &ast.PrefixExpression{
+							Operator: "-",
+							Right: &ast.InfixExpression{
+								Left:     &ast.IntegerLiteral{Value: 2},
+								Operator: "**",
+								Right: &ast.InfixExpression{
+									Left:     &ast.IntegerLiteral{Value: 3},
+									Operator: "**",
+									Right:    &ast.IntegerLiteral{Value: 2},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			input: `a | b ^ c & d << 1 + 2 <= ~e % 3;`,
+			expected: &ast.Program{
+				Statements: []ast.Statement{
+					&ast.ExpressionStatement{
+						Expression: &ast.InfixExpression{
+							Left: &ast.InfixExpression{
+								Left:     &ast.Identifier{Value: "a"},
+								Operator: "|",
+								Right: &ast.InfixExpression{
+									Left:     &ast.Identifier{Value: "b"},
+									Operator: "^",
+									Right: &ast.InfixExpression{
+										Left:     &ast.Identifier{Value: "c"},
+										Operator: "&",
+										Right: &ast.InfixExpression{
+											Left:     &ast.Identifier{Value: "d"},
+											Operator: "<<",
+											Right: &ast.InfixExpression{
+												Left: &ast.IntegerLiteral{
+													Value: 1,
+												},
+												Operator: "+",
+												Right: &ast.IntegerLiteral{
+													Value: 2,
+												},
+											},
+										},
+									},
+								},
+							},
+							Operator: "<=",
+							Right: &ast.InfixExpression{
+								Left: &ast.PrefixExpression{
+									Operator: "~",
+									Right:    &ast.Identifier{Value: "e"},
+								},
+								Operator: "%",
+								Right:    &ast.IntegerLiteral{Value: 3},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			input: `a || b && c == d;`,
+			expected: &ast.Program{
+				Statements: []ast.Statement{
+					&ast.ExpressionStatement{
+						Expression: &ast.InfixExpression{
+							Left:     &ast.Identifier{Value: "a"},
+							Operator: "||",
+							Right: &ast.InfixExpression{
+								Left:     &ast.Identifier{Value: "b"},
+								Operator: "&&",
+								Right: &ast.InfixExpression{
+									Left:     &ast.Identifier{Value: "c"},
+									Operator: "==",
+									Right:    &ast.Identifier{Value: "d"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			input: `x = y = 1 + 2;`,
+			expected: &ast.Program{
+				Statements: []ast.Statement{
+					&ast.ExpressionStatement{
+						Expression: &ast.AssignExpression{
+							Target: &ast.Identifier{Value: "x"},
+							Value: &ast.AssignExpression{
+								Target: &ast.Identifier{Value: "y"},
+								Value: &ast.InfixExpression{
+									Left:     &ast.IntegerLiteral{Value: 1},
+									Operator: "+",
+									Right:    &ast.IntegerLiteral{Value: 2},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			input: `a[0] = x == 1;`,
+			expected: &ast.Program{
+				Statements: []ast.Statement{
+					&ast.ExpressionStatement{
+						Expression: &ast.AssignExpression{
+							Target: &ast.IndexExpression{
+								Left:  &ast.Identifier{Value: "a"},
+								Index: &ast.IntegerLiteral{Value: 0},
+							},
+							Value: &ast.InfixExpression{
+								Left:     &ast.Identifier{Value: "x"},
+								Operator: "==",
+								Right:    &ast.IntegerLiteral{Value: 1},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			input: `while (x < 5) { break; continue; };`,
+			expected: &ast.Program{
+				Statements: []ast.Statement{
+					&ast.WhileStatement{
+						Condition: &ast.InfixExpression{
+							Left:     &ast.Identifier{Value: "x"},
+							Operator: "<",
+							Right:    &ast.IntegerLiteral{Value: 5},
+						},
+						Body: &ast.BlockStatement{
+							Statements: []ast.Statement{
+								&ast.BreakStatement{},
+								&ast.ContinueStatement{},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			input: `for (x in [1]) { x } 2;`,
+			expected: &ast.Program{
+				Statements: []ast.Statement{
+					&ast.ForStatement{
+						Variable: &ast.Identifier{Value: "x"},
+						Iterable: &ast.ArrayLiteral{
+							Elements: []ast.Expression{
+								&ast.IntegerLiteral{Value: 1},
+							},
+						},
+						Body: &ast.BlockStatement{
+							Statements: []ast.Statement{
+								&ast.ExpressionStatement{
+									Expression: &ast.Identifier{Value: "x"},
+								},
+							},
+						},
+					},
+					&ast.ExpressionStatement{
+						Expression: &ast.IntegerLiteral{Value: 2},
+					},
+				},
+			},
+		},
 		{
 			input: `return 5;`,
 			expected: &ast.Program{
@@ -944,12 +1154,34 @@ func Test(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: `export let m = import "lib/m"; m.f.g;`,
+			expected: &ast.Program{
+				Statements: []ast.Statement{
+					&ast.LetStatement{
+						Name:     &ast.Identifier{Value: "m"},
+						Value:    &ast.ImportExpression{Path: "lib/m"},
+						Exported: true,
+					},
+					&ast.ExpressionStatement{
+						Expression: &ast.IndexExpression{
+							Left: &ast.IndexExpression{
+								Left:  &ast.Identifier{Value: "m"},
+								Index: &ast.StringLiteral{Value: "f"},
+							},
+							Index: &ast.StringLiteral{Value: "g"},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, s := range setup {
 		lex := lexer.New(s.input)
 		p := New(lex)
-		program := p.ParseProgram()
+		program, errors := p.ParseProgram()
+		testErrors(t, errors)
 		testStatements(t, program.Statements, s.expected.Statements)
 	}
 }
@@ -1007,6 +1239,34 @@ func testStatement(
 			)
 		}
 		testLetStatement(t, a, e)
+	case *ast.WhileStatement:
+		a, ok := actual.(*ast.WhileStatement)
+		if !ok {
+			t.Fatalf(
+				"statement type mismatch. got=%T, expected=%T",
+				actual,
+				expected,
+			)
+		}
+		testWhileStatement(t, a, e)
+	case *ast.ForStatement:
+		a, ok := actual.(*ast.ForStatement)
+		if !ok {
+			t.Fatalf(
+				"statement type mismatch. got=%T, expected=%T",
+				actual,
+				expected,
+			)
+		}
+		testForStatement(t, a, e)
+	case *ast.BreakStatement, *ast.ContinueStatement:
+		if reflect.TypeOf(actual) != reflect.TypeOf(expected) {
+			t.Fatalf(
+				"statement type mismatch. got=%T, expected=%T",
+				actual,
+				expected,
+			)
+		}
 	default:
 		t.Fatal("statement type unknown")
 	}
@@ -1036,6 +1296,16 @@ func testExpression(
 			)
 		}
 		testIntegerLiteral(t, a, e)
+	case *ast.FloatLiteral:
+		a, ok := actual.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf(
+				"expression type mismatch. got=%T, expected=%T",
+				actual,
+				expected,
+			)
+		}
+		testFloatLiteral(t, a, e)
 	case *ast.Identifier:
 		a, ok := actual.(*ast.Identifier)
 		if !ok {
@@ -1096,6 +1366,16 @@ func testExpression(
 			)
 		}
 		testFunctionLiteral(t, a, e)
+	case *ast.MacroLiteral:
+		a, ok := actual.(*ast.MacroLiteral)
+		if !ok {
+			t.Fatalf(
+				"expression type mismatch. got=%T, expected=%T",
+				actual,
+				expected,
+			)
+		}
+		testMacroLiteral(t, a, e)
 	case *ast.CallExpression:
 		a, ok := actual.(*ast.CallExpression)
 		if !ok {
@@ -1136,6 +1416,16 @@ func testExpression(
 			)
 		}
 		testIndexExpression(t, a, e)
+	case *ast.AssignExpression:
+		a, ok := actual.(*ast.AssignExpression)
+		if !ok {
+			t.Fatalf(
+				"expression type mismatch. got=%T, expected=%T",
+				actual,
+				expected,
+			)
+		}
+		testAssignExpression(t, a, e)
 	case *ast.HashLiteral:
 		a, ok := actual.(*ast.HashLiteral)
 		if !ok {
@@ -1146,6 +1436,16 @@ func testExpression(
 			)
 		}
 		testHashLiteral(t, a, e)
+	case *ast.ImportExpression:
+		a, ok := actual.(*ast.ImportExpression)
+		if !ok {
+			t.Fatalf(
+				"expression type mismatch. got=%T, expected=%T",
+				actual,
+				expected,
+			)
+		}
+		testImportExpression(t, a, e)
 	default:
 		t.Fatal("expression type unknown")
 	}
@@ -1166,6 +1466,20 @@ func testIntegerLiteral(
 	}
 }
 
+func testFloatLiteral(
+	t *testing.T,
+	actual *ast.FloatLiteral,
+	expected *ast.FloatLiteral,
+) {
+	if actual.Value != expected.Value {
+		t.Fatalf(
+			"float literal value mismatch. got=%v, expected=%v",
+			actual.Value,
+			expected.Value,
+		)
+	}
+}
+
 func testIdentifier(
 	t *testing.T,
 	actual *ast.Identifier,
@@ -1266,6 +1580,15 @@ func testFunctionLiteral(
 	testBlockStatement(t, actual.Body, expected.Body)
 }
 
+func testMacroLiteral(
+	t *testing.T,
+	actual *ast.MacroLiteral,
+	expected *ast.MacroLiteral,
+) {
+	testIdentifiers(t, actual.Parameters, expected.Parameters)
+	testBlockStatement(t, actual.Body, expected.Body)
+}
+
 func testIdentifiers(
 	t *testing.T,
 	actual []*ast.Identifier,
@@ -1340,6 +1663,15 @@ func testIndexExpression(
 	testExpression(t, actual.Index, expected.Index)
 }
 
+func testAssignExpression(
+	t *testing.T,
+	actual *ast.AssignExpression,
+	expected *ast.AssignExpression,
+) {
+	testExpression(t, actual.Target, expected.Target)
+	testExpression(t, actual.Value, expected.Value)
+}
+
 func testHashLiteral(
 	t *testing.T,
 	actual *ast.HashLiteral,
@@ -1376,6 +1708,20 @@ func testHashKey(
 	testExpression(t, actual.Expression, expected.Expression)
 }
 
+func testImportExpression(
+	t *testing.T,
+	actual *ast.ImportExpression,
+	expected *ast.ImportExpression,
+) {
+	if actual.Path != expected.Path {
+		t.Fatalf(
+			"import path mismatch. got=%v, expected=%v",
+			actual.Path,
+			expected.Path,
+		)
+	}
+}
+
 func testReturnStatement(
 	t *testing.T,
 	actual *ast.ReturnStatement,
@@ -1391,4 +1737,214 @@ func testLetStatement(
 ) {
 	testIdentifier(t, actual.Name, expected.Name)
 	testExpression(t, actual.Value, expected.Value)
+	if actual.Exported != expected.Exported {
+		t.Fatalf(
+			"let statement exported mismatch. got=%v, expected=%v",
+			actual.Exported,
+			expected.Exported,
+		)
+	}
+}
+
+func testWhileStatement(
+	t *testing.T,
+	actual *ast.WhileStatement,
+	expected *ast.WhileStatement,
+) {
+	testExpression(t, actual.Condition, expected.Condition)
+	testBlockStatement(t, actual.Body, expected.Body)
+}
+
+func testForStatement(
+	t *testing.T,
+	actual *ast.ForStatement,
+	expected *ast.ForStatement,
+) {
+	testIdentifier(t, actual.Variable, expected.Variable)
+	testExpression(t, actual.Iterable, expected.Iterable)
+	testBlockStatement(t, actual.Body, expected.Body)
+}
+
+func TestSpan(t *testing.T) {
+	input := "let add = fn(x, y) {\n  x + y;\n};\nadd(1, 2);"
+	lex := lexer.NewFile("a.mk", input)
+	p := New(lex)
+	program, errors := p.ParseProgram()
+	testErrors(t, errors)
+
+	let := program.Statements[0].(*ast.LetStatement)
+	fn := let.Value.(*ast.FunctionLiteral)
+	body := fn.Body.Statements[0].(*ast.ExpressionStatement)
+	call := program.Statements[1].(*ast.ExpressionStatement).Expression
+
+	setup := []struct {
+		node  ast.Node
+		start string
+		end   string
+	}{
+		{program, "a.mk:1:1", "a.mk:4:11"},
+		{let, "a.mk:1:1", "a.mk:3:3"},
+		{let.Name, "a.mk:1:5", "a.mk:1:8"},
+		{fn, "a.mk:1:11", "a.mk:3:2"},
+		{fn.Parameters[1], "a.mk:1:17", "a.mk:1:18"},
+		{fn.Body, "a.mk:1:20", "a.mk:3:2"},
+		{body, "a.mk:2:3", "a.mk:2:9"},
+		{body.Expression, "a.mk:2:3", "a.mk:2:8"},
+		{call, "a.mk:4:1", "a.mk:4:10"},
+	}
+
+	for _, s := range setup {
+		if s.node.Pos().String() != s.start {
+			t.Fatalf(
+				"start mismatch. got=%v, expected=%v",
+				s.node.Pos(),
+				s.start,
+			)
+		}
+		if s.node.End().String() != s.end {
+			t.Fatalf(
+				"end mismatch. got=%v, expected=%v",
+				s.node.End(),
+				s.end,
+			)
+		}
+	}
+}
+
+func testErrors(t *testing.T, errors []*ParseError) {
+	if len(errors) > 0 {
+		t.Fatalf("unexpected parse error. got=%v", errors[0])
+	}
+}
+
+func TestErrors(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			`let = 5;`,
+			[]string{
+				"1:5: let must be followed by an identifier " +
+					"(expected IDENT, got =)",
+			},
+		},
+		{
+			`let x 5; (1 + 2; let y = 3;`,
+			[]string{
+				"1:7: identifier in let statement must be followed by " +
+					"assignment (expected =, got INT)",
+				"1:16: missing ) to close grouped expression " +
+					"(expected ), got ;)",
+			},
+		},
+		{
+			"let f = fn(x { x; };\nlet g = fn() { return ; };\nf(1;",
+			[]string{
+				"1:14: missing ) after fn (expected ), got {)",
+				"2:23: cannot parse prefix expression for ;",
+				"3:4: missing ) in expression list (expected ), got ;)",
+			},
+		},
+		{
+			"break;\nwhile (true) { fn() { continue; }; }\nfor (1 in x) {}",
+			[]string{
+				"1:1: break must be inside a loop",
+				"2:23: continue must be inside a loop",
+				"3:6: for must be followed by an identifier " +
+					"(expected IDENT, got INT)",
+			},
+		},
+		{
+			"let f = fn(x, x) { x };\nmacro(a, b, a) { a };",
+			[]string{
+				"1:15: duplicate parameter x",
+				"2:13: duplicate parameter a",
+			},
+		},
+		{
+			"macro x;",
+			[]string{
+				"1:7: missing ( after macro (expected (, got IDENT)",
+			},
+		},
+		{
+			"export 1;\nfn() { export let x = 1; };\nimport x;\na.1;",
+			[]string{
+				"1:8: export must be followed by let (expected LET, got INT)",
+				"2:8: export must be at top level",
+				"3:8: import must be followed by a string " +
+					"(expected STRING, got IDENT)",
+				"4:3: missing identifier after . (expected IDENT, got INT)",
+			},
+		},
+		{
+			"while (x) { let a = [1, if (x) { continue; }]; }\n" +
+				"for (x in y) { x + if (x) { break; } else { 0 }; }\n" +
+				"while (x) { f(if (x) { if (y) { break; } }); }\n" +
+				"while (x) { let a = if (x) { 1 } else { break; }; }",
+			[]string{
+				"1:34: continue must not be inside an expression",
+				"2:29: break must not be inside an expression",
+				"3:33: break must not be inside an expression",
+				"4:41: break must not be inside an expression",
+			},
+		},
+		{
+			"1 = 2;\nf() = 3;",
+			[]string{
+				"1:3: invalid assignment target",
+				"2:5: invalid assignment target",
+			},
+		},
+		{
+			"let a = 1 # 2;\n}",
+			[]string{
+				"1:11: unexpected character \"#\"",
+				"2:1: cannot parse prefix expression for }",
+			},
+		},
+	}
+
+	for _, s := range setup {
+		lex := lexer.New(s.input)
+		p := New(lex)
+		_, actual := p.ParseProgram()
+		if len(actual) != len(s.expected) {
+			t.Fatalf(
+				"number of errors mismatch. got=%v, expected=%v",
+				actual,
+				s.expected,
+			)
+		}
+		for i, e := range s.expected {
+			if actual[i].Error() != e {
+				t.Fatalf(
+					"error mismatch. got=%q, expected=%q",
+					actual[i].Error(),
+					e,
+				)
+			}
+		}
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	input := `let a = ; let b = 2; if (b { 3 }; b;`
+	lex := lexer.New(input)
+	p := New(lex)
+	program, errors := p.ParseProgram()
+	if len(errors) != 2 {
+		t.Fatalf("number of errors mismatch. got=%v, expected=2", errors)
+	}
+	expected := []ast.Statement{
+		&ast.LetStatement{
+			Name:  &ast.Identifier{Value: "b"},
+			Value: &ast.IntegerLiteral{Value: 2},
+		},
+		&ast.ExpressionStatement{
+			Expression: &ast.Identifier{Value: "b"},
+		},
+	}
+	testStatements(t, program.Statements, expected)
 }
@@ -2,26 +2,24 @@ package parser
 
 import "github.com/vincentlabelle/monkey/token"
 
-const (
-	LOWEST = iota
-	EQUALS
-	LESSGREATER
-	SUM
-	PRODUCT
-	PREFIX
-	CALL
-	INDEX
-)
-
-var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NE:       EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+var infixOperators = []token.TokenType{
+	token.PLUS,
+	token.MINUS,
+	token.ASTERISK,
+	token.SLASH,
+	token.PERCENT,
+	token.POWER,
+	token.EQ,
+	token.NE,
+	token.LT,
+	token.GT,
+	token.LE,
+	token.GE,
+	token.AMPERSAND,
+	token.PIPE,
+	token.CARET,
+	token.SHL,
+	token.SHR,
+	token.AND,
+	token.OR,
 }
@@ -0,0 +1,157 @@
+package repl
+
+import (
+	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/compiler"
+	"github.com/vincentlabelle/monkey/evaluator"
+	"github.com/vincentlabelle/monkey/module"
+	"github.com/vincentlabelle/monkey/object"
+	"github.com/vincentlabelle/monkey/symbol"
+	"github.com/vincentlabelle/monkey/vm"
+)
+
+type Engine interface {
+	Execute(program *ast.Program) (object.Object, error)
+	SetArguments(values []string)
+}
+
+func NewEngine(
+	name string,
+	loader *module.Loader,
+	level compiler.Level,
+) (Engine, bool) {
+	switch name {
+	case "eval":
+		return NewEvaluator(loader), true
+	case "vm":
+		return NewMachine(loader, level), true
+	}
+	return nil, false
+}
+
+func expand(
+	program *ast.Program,
+	macros *object.Environment,
+) (*ast.Program, error) {
+	evaluator.DefineMacros(program, macros)
+	return evaluator.ExpandMacros(program, macros)
+}
+
+type Evaluator struct {
+	env      *object.Environment
+	macros   *object.Environment
+	importer *evaluator.Importer
+}
+
+func NewEvaluator(loader *module.Loader) *Evaluator {
+	e := &Evaluator{
+		env:    object.NewEnvironment(),
+		macros: object.NewEnvironment(),
+	}
+	if loader != nil {
+		e.importer = evaluator.NewImporter(loader)
+		e.env.SetImporter(e.importer)
+	}
+	return e
+}
+
+func (e *Evaluator) SetArguments(values []string) {
+	e.env.SetArguments(values)
+	if e.importer != nil {
+		e.importer.SetArguments(values)
+	}
+}
+
+func (e *Evaluator) Execute(program *ast.Program) (object.Object, error) {
+	program, err := expand(program, e.macros)
+	if err != nil {
+		return nil, err
+	}
+	return evaluator.Eval(program, e.env)
+}
+
+type Machine struct {
+	loader    *module.Loader
+	level     compiler.Level
+	macros    *object.Environment
+	table     *symbol.SymbolTable
+	constants []object.Object
+	globals   []object.Object
+	arguments []string
+}
+
+func NewMachine(loader *module.Loader, level compiler.Level) *Machine {
+	return &Machine{
+		loader:    loader,
+		level:     level,
+		macros:    object.NewEnvironment(),
+		table:     symbol.NewTable(),
+		constants: []object.Object{},
+		globals:   make([]object.Object, vm.GlobalsSize),
+		arguments: []string{},
+	}
+}
+
+func (m *Machine) SetArguments(values []string) {
+	m.arguments = values
+}
+
+func (m *Machine) Execute(program *ast.Program) (object.Object, error) {
+	program, err := expand(program, m.macros)
+	if err != nil {
+		return nil, err
+	}
+	code, err := m.compile(program)
+	if err != nil {
+		return nil, err
+	}
+	machine := vm.NewWithGlobals(code, m.globals)
+	machine.SetArguments(m.arguments)
+	if err := machine.Run(); err != nil {
+		return nil, err
+	}
+	if !endsWithExpression(program) {
+		return nil, nil
+	}
+	return machine.LastPopped(), nil
+}
+
+func (m *Machine) Compile(program *ast.Program) (*compiler.Bytecode, error) {
+	program, err := expand(program, m.macros)
+	if err != nil {
+		return nil, err
+	}
+	return m.compile(program)
+}
+
+func (m *Machine) compile(program *ast.Program) (*compiler.Bytecode, error) {
+	c := compiler.NewWithState(m.table, m.constants)
+	c.SetLoader(m.loader)
+	c.SetLevel(m.level)
+	code, err := c.Compile(program)
+	if err != nil {
+		return nil, err
+	}
+	m.constants = code.Constants
+	return code, nil
+}
+
+func endsWithExpression(program *ast.Program) bool {
+	if len(program.Statements) == 0 {
+		return false
+	}
+	last := program.Statements[len(program.Statements)-1]
+	_, ok := last.(*ast.ExpressionStatement)
+	return ok
+}
+
+func ErrorKind(err error) string {
+	switch err.(type) {
+	case *compiler.Error:
+		return "compile error"
+	case *evaluator.ExpansionError:
+		return "macro error"
+	default:
+		return "runtime error"
+	}
+}
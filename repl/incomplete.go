@@ -0,0 +1,35 @@
+package repl
+
+import (
+	"strings"
+
+	"github.com/vincentlabelle/monkey/lexer"
+	"github.com/vincentlabelle/monkey/token"
+)
+
+var depths = map[token.TokenType]int{
+	token.LPAREN:   1,
+	token.LBRACKET: 1,
+	token.LBRACE:   1,
+	token.RPAREN:   -1,
+	token.RBRACKET: -1,
+	token.RBRACE:   -1,
+}
+
+func isIncomplete(input string) bool {
+	lex := lexer.New(input)
+	depth := 0
+	for tok := lex.NextToken(); tok.Type != token.EOF; tok = lex.NextToken() {
+		depth += depths[tok.Type]
+	}
+	return depth > 0 || isUnterminated(lex.Errors())
+}
+
+func isUnterminated(errors []*lexer.Error) bool {
+	for _, err := range errors {
+		if strings.HasPrefix(err.Message, "unterminated") {
+			return true
+		}
+	}
+	return false
+}
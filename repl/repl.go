@@ -6,33 +6,36 @@ import (
 	"io"
 	"log"
 
-	"github.com/vincentlabelle/monkey/evaluator"
 	"github.com/vincentlabelle/monkey/lexer"
-	"github.com/vincentlabelle/monkey/object"
 	"github.com/vincentlabelle/monkey/parser"
 )
 
-const PROMPT = ">> "
+const (
+	PROMPT       = ">> "
+	CONTINUATION = ".. "
+)
 
-func Start(in io.Reader, out io.Writer) {
+func Start(in io.Reader, out io.Writer, engine Engine) {
 	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
 
 	for {
-		fmt.Fprint(out, PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
-			return
-		}
-		line := scanner.Text()
-		if line == "exit()" {
+		input, ok := read(scanner, out)
+		if !ok || input == "exit()" {
 			return
 		}
 
-		lex := lexer.New(line)
+		lex := lexer.New(input)
 		p := parser.New(lex)
-		program := p.ParseProgram()
-		evaluated := evaluator.Eval(program, env)
+		program, errors := p.ParseProgram()
+		if len(errors) > 0 {
+			printErrors(out, errors)
+			continue
+		}
+		evaluated, err := engine.Execute(program)
+		if err != nil {
+			printError(out, err)
+			continue
+		}
 
 		if evaluated != nil {
 			_, err := io.WriteString(out, evaluated.Inspect()+"\n")
@@ -42,3 +45,36 @@ func Start(in io.Reader, out io.Writer) {
 		}
 	}
 }
+
+func read(scanner *bufio.Scanner, out io.Writer) (string, bool) {
+	fmt.Fprint(out, PROMPT)
+	if !scanner.Scan() {
+		return "", false
+	}
+	input := scanner.Text()
+	for isIncomplete(input) {
+		fmt.Fprint(out, CONTINUATION)
+		if !scanner.Scan() || scanner.Text() == "" {
+			break
+		}
+		input += "\n" + scanner.Text()
+	}
+	return input, true
+}
+
+func printErrors(out io.Writer, errors []*parser.ParseError) {
+	for _, err := range errors {
+		_, err_ := io.WriteString(out, "syntax error: "+err.Error()+"\n")
+		if err_ != nil {
+			log.Fatal(err_)
+		}
+	}
+}
+
+func printError(out io.Writer, err error) {
+	message := ErrorKind(err) + ": " + err.Error() + "\n"
+	_, err_ := io.WriteString(out, message)
+	if err_ != nil {
+		log.Fatal(err_)
+	}
+}
@@ -0,0 +1,94 @@
+package repl
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/vincentlabelle/monkey/compiler"
+)
+
+func TestStart(t *testing.T) {
+	input := strings.Join([]string{
+		`let a = 1;`,
+		`let add = fn(x) { x + a; };`,
+		`add(2);`,
+		`let b = add(a);`,
+		`[a, b, "c"];`,
+		`a + true;`,
+		`b;`,
+		`let f = fn(x) {`,
+		`  /* multi`,
+		`  line */`,
+		`  [x,`,
+		`   x];`,
+		`};`,
+		`f(b);`,
+		`let = 5;`,
+		`c;`,
+		`let z = a + true;`,
+		`z;`,
+		`b;`,
+		`(1 +`,
+		``,
+		`b;`,
+		`let twice = macro(x) { quote(unquote(x) * 2) };`,
+		`twice(b + 1);`,
+		`twice();`,
+		`let r = fn() { r() };`,
+		`r();`,
+		`b;`,
+	}, "\n")
+	expected := strings.Join([]string{
+		PROMPT + PROMPT + PROMPT + "3",
+		PROMPT + PROMPT + "[1, 2, c]",
+		PROMPT + "error: ",
+		PROMPT + "2",
+		PROMPT + strings.Repeat(CONTINUATION, 5) + PROMPT + "[2, 2]",
+		PROMPT + "error: ",
+		PROMPT + "error: ",
+		PROMPT + "error: ",
+		PROMPT + "error: ",
+		PROMPT + "2",
+		PROMPT + CONTINUATION + "error: ",
+		PROMPT + "2",
+		PROMPT + PROMPT + "6",
+		PROMPT + "error: ",
+		PROMPT + PROMPT + "error: ",
+		PROMPT + "2",
+		PROMPT,
+	}, "\n")
+
+	for _, name := range []string{"eval", "vm"} {
+		engine, ok := NewEngine(name, nil, compiler.Default)
+		if !ok {
+			t.Fatalf("engine %v is undefined", name)
+		}
+		var out bytes.Buffer
+		Start(strings.NewReader(input), &out, engine)
+		actual := removeErrorDetails(out.String())
+		if actual != expected {
+			t.Fatalf(
+				"output mismatch for %v. got=%q, expected=%q",
+				name,
+				actual,
+				expected,
+			)
+		}
+	}
+}
+
+var details = regexp.MustCompile(`\w+ error: .*`)
+
+func removeErrorDetails(output string) string {
+	lines := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "\t") {
+			continue
+		}
+		line = details.ReplaceAllString(line, "error: ")
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
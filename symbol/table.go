@@ -36,6 +36,9 @@ func NewInnerTable(outer *SymbolTable) *SymbolTable {
 }
 
 func (s *SymbolTable) Define(name string) Symbol {
+	if sym, ok := s.store[name]; ok && sym.Scope == s.getScope() {
+		return sym // Rebinding reuses the existing slot!
+	}
 	sym := Symbol{
 		Name:  name,
 		Scope: s.getScope(),
@@ -44,6 +44,37 @@ func TestDefine(t *testing.T) {
 	}
 }
 
+func TestDefineTwice(t *testing.T) {
+	global := NewTable()
+	global.Define("a")
+	global.Define("b")
+	local := NewInnerTable(global)
+	local.DefineFunctionName("a")
+
+	setup := []struct {
+		table    *SymbolTable
+		expected Symbol
+	}{
+		{global, Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{local, Symbol{Name: "a", Scope: LocalScope, Index: 0}},
+		{local, Symbol{Name: "a", Scope: LocalScope, Index: 0}},
+	}
+
+	for _, s := range setup {
+		a := s.table.Define(s.expected.Name)
+		if a != s.expected {
+			t.Fatalf("symbol mismatch. got=%v, expected=%v", a, s.expected)
+		}
+	}
+	if global.CountDefinitions() != 2 {
+		t.Fatalf(
+			"number of definitions mismatch. got=%v, expected=%v",
+			global.CountDefinitions(),
+			2,
+		)
+	}
+}
+
 func TestResolve(t *testing.T) {
 	global := NewTable()
 	global.Define("a")
@@ -9,9 +9,15 @@ var Unary = map[byte]TokenType{
 	'!': BANG,
 	'<': LT,
 	'>': GT,
+	'%': PERCENT,
+	'&': AMPERSAND,
+	'|': PIPE,
+	'^': CARET,
+	'~': TILDE,
 	',': COMMA,
 	';': SEMICOLON,
 	':': COLON,
+	'.': DOT,
 	'(': LPAREN,
 	')': RPAREN,
 	'{': LBRACE,
@@ -23,14 +29,29 @@ var Unary = map[byte]TokenType{
 var Binary = map[string]TokenType{
 	"==": EQ,
 	"!=": NE,
+	"&&": AND,
+	"||": OR,
+	"<=": LE,
+	">=": GE,
+	"**": POWER,
+	"<<": SHL,
+	">>": SHR,
 }
 
 var Keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"macro":    MACRO,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"import":   IMPORT,
+	"export":   EXPORT,
 }
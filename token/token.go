@@ -3,4 +3,6 @@ package token
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position
+	End     Position
 }
@@ -11,11 +11,24 @@ const (
 	BANG      = "!"
 	EQ        = "=="
 	NE        = "!="
+	AND       = "&&"
+	OR        = "||"
 	LT        = "<"
 	GT        = ">"
+	LE        = "<="
+	GE        = ">="
+	PERCENT   = "%"
+	POWER     = "**"
+	AMPERSAND = "&"
+	PIPE      = "|"
+	CARET     = "^"
+	TILDE     = "~"
+	SHL       = "<<"
+	SHR       = ">>"
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	DOT       = "."
 	LPAREN    = "("
 	RPAREN    = ")"
 	LBRACE    = "{"
@@ -23,15 +36,25 @@ const (
 	LBRACKET  = "["
 	RBRACKET  = "]"
 	INT       = "INT"
+	FLOAT     = "FLOAT"
 	STRING    = "STRING"
 	FUNCTION  = "FUNCTION"
+	MACRO     = "MACRO"
 	LET       = "LET"
 	TRUE      = "TRUE"
 	FALSE     = "FALSE"
 	IF        = "IF"
 	ELSE      = "ELSE"
 	RETURN    = "RETURN"
+	WHILE     = "WHILE"
+	FOR       = "FOR"
+	IN        = "IN"
+	BREAK     = "BREAK"
+	CONTINUE  = "CONTINUE"
+	IMPORT    = "IMPORT"
+	EXPORT    = "EXPORT"
 	IDENT     = "IDENT"
+	COMMENT   = "COMMENT"
 	ILLEGAL   = "ILLEGAL"
 	EOF       = "EOF"
 )
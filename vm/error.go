@@ -0,0 +1,85 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/token"
+)
+
+const traceSize = 20
+
+type RuntimeError struct {
+	Message string
+	Op      code.Opcode
+	Pos     token.Position
+	Trace   []TraceEntry
+}
+
+type TraceEntry struct {
+	Function string
+	Pos      token.Position
+}
+
+func (vm *VM) newRuntimeError(op code.Opcode, err error) *RuntimeError {
+	trace := vm.trace()
+	return &RuntimeError{
+		Message: err.Error(),
+		Op:      op,
+		Pos:     trace[0].Pos,
+		Trace:   trace,
+	}
+}
+
+func (vm *VM) trace() []TraceEntry {
+	trace := []TraceEntry{}
+	for i := vm.framesIndex - 1; i >= 0; i-- {
+		trace = append(trace, newTraceEntry(vm.frames[i], i))
+	}
+	return trace
+}
+
+func newTraceEntry(frame *Frame, index int) TraceEntry {
+	return TraceEntry{
+		Function: getFunctionName(frame, index),
+		Pos:      frame.Closure.Fn.SourceMap.Lookup(frame.OpIndex),
+	}
+}
+
+func getFunctionName(frame *Frame, index int) string {
+	if index == 0 {
+		return "<main>"
+	}
+	if frame.Closure.Fn.Name == "" {
+		return "<anonymous>"
+	}
+	return frame.Closure.Fn.Name
+}
+
+func (e *RuntimeError) Error() string {
+	var b strings.Builder
+	if e.Pos.IsValid() {
+		fmt.Fprintf(&b, "%v: ", e.Pos)
+	}
+	fmt.Fprintf(&b, "%v (at %v)", e.Message, e.Op)
+	for i, entry := range e.Trace {
+		if elided := len(e.Trace) - traceSize; elided > 0 {
+			if i == traceSize/2 {
+				fmt.Fprintf(&b, "\n\t... %v more frames", elided)
+			}
+			if i >= traceSize/2 && i < len(e.Trace)-traceSize/2 {
+				continue
+			}
+		}
+		b.WriteString("\n\tat " + entry.String())
+	}
+	return b.String()
+}
+
+func (e TraceEntry) String() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%v (%v)", e.Function, e.Pos)
+	}
+	return e.Function
+}
@@ -8,6 +8,7 @@ import (
 type Frame struct {
 	Closure        *object.Closure
 	InsIndex       int
+	OpIndex        int
 	BaseStackIndex int
 }
 
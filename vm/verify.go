@@ -0,0 +1,388 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/compiler"
+	"github.com/vincentlabelle/monkey/object"
+)
+
+type stream struct {
+	index        int
+	name         string
+	instructions code.Instructions
+	decoded      []instruction
+	indexes      map[int]int
+	locals       int
+	parameters   int
+	free         int
+	function     bool
+}
+
+type instruction struct {
+	offset   int
+	op       code.Opcode
+	operands []int
+}
+
+type successor struct {
+	index int
+	depth int
+}
+
+type effect struct {
+	pops   int
+	pushes int
+}
+
+var effects = map[code.Opcode]effect{
+	code.OpConstant:       {0, 1},
+	code.OpTrue:           {0, 1},
+	code.OpFalse:          {0, 1},
+	code.OpNull:           {0, 1},
+	code.OpIndex:          {2, 1},
+	code.OpPop:            {1, 0},
+	code.OpJump:           {0, 0},
+	code.OpJumpIf:         {1, 0},
+	code.OpSetGlobal:      {1, 0},
+	code.OpGetGlobal:      {0, 1},
+	code.OpSetLocal:       {1, 0},
+	code.OpGetLocal:       {0, 1},
+	code.OpGetBuiltin:     {0, 1},
+	code.OpGetFree:        {0, 1},
+	code.OpReturnValue:    {1, 0},
+	code.OpReturn:         {0, 0},
+	code.OpCurrentClosure: {0, 1},
+	code.OpIter:           {1, 1},
+	code.OpSetFree:        {1, 0},
+	code.OpSetIndex:       {3, 1},
+	code.OpCaptureLocal:   {0, 1},
+	code.OpCaptureFree:    {0, 1},
+	code.OpImport:         {0, 0},
+	code.OpDup:            {1, 2},
+}
+
+func Load(data []byte) (*compiler.Bytecode, error) {
+	bytecode := &compiler.Bytecode{}
+	if err := bytecode.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	if err := Verify(bytecode); err != nil {
+		return nil, err
+	}
+	return bytecode, nil
+}
+
+func Verify(bytecode *compiler.Bytecode) error {
+	streams := getStreams(bytecode)
+	for _, s := range streams {
+		if err := s.decode(); err != nil {
+			return err
+		}
+	}
+	free, err := countFree(streams)
+	if err != nil {
+		return err
+	}
+	for _, s := range streams {
+		s.free = free[s.index]
+		if err := s.verify(bytecode.Constants); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getStreams(bytecode *compiler.Bytecode) []*stream {
+	streams := []*stream{
+		{index: -1, name: "<main>", instructions: bytecode.Instructions},
+	}
+	for i, obj := range bytecode.Constants {
+		fn, ok := obj.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+		streams = append(streams, &stream{
+			index:        i,
+			name:         fmt.Sprintf("%v (constant %v)", getName(fn), i),
+			instructions: fn.Instructions,
+			locals:       fn.NumLocals,
+			parameters:   fn.NumParameters,
+			function:     true,
+		})
+	}
+	return streams
+}
+
+func getName(fn *object.CompiledFunction) string {
+	if fn.Name == "" {
+		return "fn <anonymous>"
+	}
+	return "fn " + fn.Name
+}
+
+func newVerifyError(s *stream, offset int, format string, a ...any) error {
+	message := fmt.Sprintf(format, a...)
+	return fmt.Errorf(
+		"cannot verify bytecode; %v at %04d: %v",
+		s.name,
+		offset,
+		message,
+	)
+}
+
+func (s *stream) decode() error {
+	s.indexes = map[int]int{}
+	for offset := 0; offset < len(s.instructions); {
+		width, err := s.getWidth(offset)
+		if err != nil {
+			return err
+		}
+		op, operands, _ := code.Unmake(s.instructions[offset:])
+		s.indexes[offset] = len(s.decoded)
+		s.decoded = append(s.decoded, instruction{
+			offset:   offset,
+			op:       op,
+			operands: operands,
+		})
+		offset += width
+	}
+	return nil
+}
+
+func (s *stream) getWidth(offset int) (int, error) {
+	remain := s.instructions[offset:]
+	if code.Opcode(remain[0]) != code.OpWide {
+		return s.checkWidth(offset, remain[0], code.Width)
+	}
+	if len(remain) < 2 {
+		return 0, newVerifyError(s, offset, "truncated %v", code.OpWide)
+	}
+	op := remain[1]
+	if code.IsDefined(op) && !code.CanWiden(code.Opcode(op)) {
+		message := "%v can't be widened"
+		return 0, newVerifyError(s, offset, message, code.Opcode(op))
+	}
+	return s.checkWidth(offset, op, code.WideWidth)
+}
+
+func (s *stream) checkWidth(
+	offset int,
+	op byte,
+	getWidth func(code.Opcode) int,
+) (int, error) {
+	if !code.IsDefined(op) {
+		return 0, newVerifyError(s, offset, "undefined opcode %v", op)
+	}
+	width := getWidth(code.Opcode(op))
+	if offset+width > len(s.instructions) {
+		return 0, newVerifyError(s, offset, "truncated %v", code.Opcode(op))
+	}
+	return width, nil
+}
+
+func countFree(streams []*stream) (map[int]int, error) {
+	functions := map[int]bool{}
+	for _, s := range streams {
+		functions[s.index] = s.function
+	}
+	free := map[int]int{}
+	for _, s := range streams {
+		for _, ins := range s.decoded {
+			if ins.op != code.OpClosure {
+				continue
+			}
+			index, count := ins.operands[0], ins.operands[1]
+			if !functions[index] {
+				message := "constant %v isn't a function"
+				return nil, newVerifyError(s, ins.offset, message, index)
+			}
+			if previous, ok := free[index]; ok && previous != count {
+				message := "closure captures %v free variables, expected %v"
+				return nil, newVerifyError(
+					s,
+					ins.offset,
+					message,
+					count,
+					previous,
+				)
+			}
+			free[index] = count
+		}
+	}
+	return free, nil
+}
+
+func (s *stream) verify(constants []object.Object) error {
+	if s.locals < 0 {
+		return newVerifyError(s, 0, "negative number of locals")
+	}
+	if s.parameters < 0 {
+		return newVerifyError(s, 0, "negative number of parameters")
+	}
+	if s.parameters > s.locals {
+		message := "%v parameters exceed %v locals"
+		return newVerifyError(s, 0, message, s.parameters, s.locals)
+	}
+	for _, ins := range s.decoded {
+		if err := s.verifyOperands(ins, constants); err != nil {
+			return err
+		}
+	}
+	return s.verifyStack()
+}
+
+func (s *stream) verifyOperands(
+	ins instruction,
+	constants []object.Object,
+) error {
+	if kind, limit, ok := s.getLimit(ins.op, constants); ok {
+		if operand := ins.operands[0]; operand >= limit {
+			message := "undefined %v %v"
+			return newVerifyError(s, ins.offset, message, kind, operand)
+		}
+	}
+	if ins.op == code.OpCurrentClosure && !s.function {
+		message := "current closure outside function"
+		return newVerifyError(s, ins.offset, message)
+	}
+	if ins.op == code.OpQuote && !isQuote(constants[ins.operands[0]]) {
+		message := "constant %v isn't a quote"
+		return newVerifyError(s, ins.offset, message, ins.operands[0])
+	}
+	return s.verifyTarget(ins)
+}
+
+func (s *stream) getLimit(
+	op code.Opcode,
+	constants []object.Object,
+) (string, int, bool) {
+	switch op {
+	case code.OpConstant, code.OpClosure, code.OpQuote:
+		return "constant", len(constants), true
+	case code.OpGetBuiltin:
+		return "built-in", len(object.Builtins), true
+	case code.OpGetGlobal, code.OpSetGlobal, code.OpImport:
+		return "global", GlobalsSize, true
+	case code.OpGetLocal, code.OpSetLocal, code.OpCaptureLocal:
+		return "local", s.locals, true
+	case code.OpGetFree, code.OpSetFree, code.OpCaptureFree:
+		return "free variable", s.free, true
+	}
+	return "", 0, false
+}
+
+func isQuote(obj object.Object) bool {
+	_, ok := obj.(*object.Quote)
+	return ok
+}
+
+func (s *stream) verifyTarget(ins instruction) error {
+	i, ok := code.JumpOperand[ins.op]
+	if !ok {
+		return nil
+	}
+	target := ins.operands[i]
+	if _, ok := s.indexes[target]; ok || target == len(s.instructions) {
+		return nil
+	}
+	message := "jump target %v isn't an instruction boundary"
+	return newVerifyError(s, ins.offset, message, target)
+}
+
+func (s *stream) verifyStack() error {
+	depths := make([]int, len(s.decoded)+1)
+	for i := range depths {
+		depths[i] = -1
+	}
+	depths[0] = 0
+	work := []int{0}
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		if i == len(s.decoded) {
+			if s.function {
+				offset := len(s.instructions)
+				return newVerifyError(s, offset, "missing return")
+			}
+			continue
+		}
+		ins := s.decoded[i]
+		e := getEffect(ins)
+		if depths[i] < e.pops {
+			return newVerifyError(s, ins.offset, "stack underflow")
+		}
+		depth := depths[i] - e.pops + e.pushes
+		for _, next := range s.getSuccessors(i, ins, depth) {
+			if depths[next.index] == -1 {
+				depths[next.index] = next.depth
+				work = append(work, next.index)
+			} else if depths[next.index] != next.depth {
+				message := "inconsistent stack depth %v, expected %v"
+				return newVerifyError(
+					s,
+					s.getOffset(next.index),
+					message,
+					next.depth,
+					depths[next.index],
+				)
+			}
+		}
+	}
+	return nil
+}
+
+func getEffect(ins instruction) effect {
+	switch ins.op {
+	case code.OpArray:
+		return effect{ins.operands[0], 1}
+	case code.OpHash:
+		return effect{2 * ins.operands[0], 1}
+	case code.OpCall:
+		return effect{ins.operands[0] + 1, 1}
+	case code.OpClosure, code.OpQuote:
+		return effect{ins.operands[1], 1}
+	}
+	if _, ok := code.InfixOperatorReverse[ins.op]; ok {
+		return effect{2, 1}
+	}
+	if _, ok := code.PrefixOperatorReverse[ins.op]; ok {
+		return effect{1, 1}
+	}
+	return effects[ins.op]
+}
+
+func (s *stream) getSuccessors(
+	i int,
+	ins instruction,
+	depth int,
+) []successor {
+	switch ins.op {
+	case code.OpReturnValue, code.OpReturn:
+		return []successor{}
+	case code.OpJump:
+		return []successor{{s.getIndex(ins.operands[0]), depth}}
+	case code.OpJumpIf:
+		target := s.getIndex(ins.operands[0])
+		return []successor{{i + 1, depth}, {target, depth}}
+	case code.OpImport:
+		target := s.getIndex(ins.operands[1])
+		return []successor{{i + 1, depth}, {target, depth + 1}}
+	}
+	return []successor{{i + 1, depth}}
+}
+
+func (s *stream) getIndex(offset int) int {
+	if offset == len(s.instructions) {
+		return len(s.decoded)
+	}
+	return s.indexes[offset]
+}
+
+func (s *stream) getOffset(index int) int {
+	if index == len(s.decoded) {
+		return len(s.instructions)
+	}
+	return s.decoded[index].offset
+}
@@ -0,0 +1,191 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/vincentlabelle/monkey/code"
+	"github.com/vincentlabelle/monkey/compiler"
+	"github.com/vincentlabelle/monkey/object"
+)
+
+func TestVerify(t *testing.T) {
+	function := func(pieces ...[]byte) *object.CompiledFunction {
+		return &object.CompiledFunction{
+			Name:         "f",
+			Instructions: concatenate(pieces...),
+		}
+	}
+	setup := []struct {
+		instructions code.Instructions
+		constants    []object.Object
+		expected     string
+	}{
+		{
+			code.Instructions{255},
+			nil,
+			"<main> at 0000: undefined opcode 255",
+		},
+		{
+			concatenate(
+				code.Make(code.OpTrue),
+				code.Make(code.OpConstant, 0)[:2],
+			),
+			nil,
+			"<main> at 0001: truncated OpConstant",
+		},
+		{
+			code.Instructions{byte(code.OpWide)},
+			nil,
+			"<main> at 0000: truncated OpWide",
+		},
+		{
+			code.Instructions{byte(code.OpWide), byte(code.OpPop)},
+			nil,
+			"<main> at 0000: OpPop can't be widened",
+		},
+		{
+			code.Make(code.OpConstant, 65536)[:5],
+			nil,
+			"<main> at 0000: truncated OpConstant",
+		},
+		{
+			concatenate(code.Make(code.OpConstant, 1)),
+			[]object.Object{&object.Integer{Value: 1}},
+			"<main> at 0000: undefined constant 1",
+		},
+		{
+			concatenate(code.Make(code.OpGetBuiltin, 200)),
+			nil,
+			"<main> at 0000: undefined built-in 200",
+		},
+		{
+			concatenate(code.Make(code.OpGetGlobal, GlobalsSize)),
+			nil,
+			"<main> at 0000: undefined global 65536",
+		},
+		{
+			concatenate(code.Make(code.OpGetLocal, 0)),
+			nil,
+			"<main> at 0000: undefined local 0",
+		},
+		{
+			concatenate(code.Make(code.OpCurrentClosure)),
+			nil,
+			"<main> at 0000: current closure outside function",
+		},
+		{
+			concatenate(code.Make(code.OpTrue), code.Make(code.OpJump, 2)),
+			nil,
+			"<main> at 0001: jump target 2 isn't an instruction boundary",
+		},
+		{
+			concatenate(code.Make(code.OpPop)),
+			nil,
+			"<main> at 0000: stack underflow",
+		},
+		{
+			concatenate(
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpIf, 7),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpNull),
+			),
+			[]object.Object{&object.Integer{Value: 1}},
+			"<main> at 0007: inconsistent stack depth 1, expected 0",
+		},
+		{
+			concatenate(code.Make(code.OpClosure, 0, 0)),
+			[]object.Object{&object.Integer{Value: 1}},
+			"<main> at 0000: constant 0 isn't a function",
+		},
+		{
+			concatenate(
+				code.Make(code.OpTrue),
+				code.Make(code.OpQuote, 0, 1),
+			),
+			[]object.Object{&object.Integer{Value: 1}},
+			"<main> at 0001: constant 0 isn't a quote",
+		},
+		{
+			concatenate(
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpClosure, 0, 1),
+			),
+			[]object.Object{function(code.Make(code.OpReturn))},
+			"<main> at 0004: closure captures 1 free variables, expected 0",
+		},
+		{
+			concatenate(code.Make(code.OpClosure, 0, 0)),
+			[]object.Object{
+				function(
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpReturnValue),
+				),
+			},
+			"fn f (constant 0) at 0000: undefined free variable 0",
+		},
+		{
+			concatenate(code.Make(code.OpClosure, 0, 0)),
+			[]object.Object{function(code.Make(code.OpTrue))},
+			"fn f (constant 0) at 0001: missing return",
+		},
+		{
+			concatenate(code.Make(code.OpClosure, 0, 0)),
+			[]object.Object{
+				&object.CompiledFunction{
+					Name:          "f",
+					Instructions:  concatenate(code.Make(code.OpReturn)),
+					NumLocals:     1,
+					NumParameters: 3,
+				},
+			},
+			"fn f (constant 0) at 0000: 3 parameters exceed 1 locals",
+		},
+	}
+
+	for _, s := range setup {
+		bytecode := &compiler.Bytecode{
+			Instructions: s.instructions,
+			Constants:    s.constants,
+		}
+		err := Verify(bytecode)
+		expected := "cannot verify bytecode; " + s.expected
+		if err == nil || err.Error() != expected {
+			t.Fatalf("error mismatch. got=%v, expected=%q", err, expected)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	bytecode := compile(t, `let f = fn(x) { x * 2 }; f(1);`)
+	data, err := bytecode.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	loaded, err := Load(data)
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	vm := New(loaded)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	testObject(t, vm.LastPopped(), &object.Integer{Value: 2})
+
+	bytecode.Instructions = concatenate(code.Make(code.OpJump, 1))
+	data, _ = bytecode.MarshalBinary()
+	_, err = Load(data)
+	expected := "cannot verify bytecode; " +
+		"<main> at 0000: jump target 1 isn't an instruction boundary"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("error mismatch. got=%v, expected=%q", err, expected)
+	}
+}
+
+func concatenate(pieces ...[]byte) code.Instructions {
+	instructions := code.Instructions{}
+	for _, piece := range pieces {
+		instructions = append(instructions, piece...)
+	}
+	return instructions
+}
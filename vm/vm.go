@@ -1,7 +1,7 @@
 package vm
 
 import (
-	"log"
+	"fmt"
 	"slices"
 
 	"github.com/vincentlabelle/monkey/code"
@@ -11,7 +11,7 @@ import (
 )
 
 const (
-	GlobalsSize = 65536
+	GlobalsSize = compiler.GlobalsSize
 	StackSize   = 2048
 	FramesSize  = 1024
 )
@@ -23,56 +23,75 @@ type VM struct {
 	frames      []*Frame
 	framesIndex int
 	constants   []object.Object
+	builtins    []*object.Builtin
 }
 
 func New(code *compiler.Bytecode) *VM {
+	return NewWithGlobals(code, make([]object.Object, GlobalsSize))
+}
+
+func NewWithGlobals(code *compiler.Bytecode, globals []object.Object) *VM {
 	vm := &VM{
-		globals:   make([]object.Object, GlobalsSize),
+		globals:   globals,
 		stack:     make([]object.Object, StackSize),
 		frames:    make([]*Frame, FramesSize),
 		constants: code.Constants,
+		builtins:  object.NewBuiltins([]string{}),
 	}
-	vm.pushInitialFrame(code.Instructions)
+	vm.pushInitialFrame(code)
 	return vm
 }
 
-func (vm *VM) pushInitialFrame(instructions code.Instructions) {
+func (vm *VM) SetArguments(values []string) {
+	vm.builtins = object.NewBuiltins(values)
+}
+
+func (vm *VM) pushInitialFrame(code *compiler.Bytecode) {
 	frame := &Frame{
 		Closure: &object.Closure{
 			Fn: &object.CompiledFunction{
-				Instructions: instructions,
+				Instructions: code.Instructions,
+				SourceMap:    code.SourceMap,
 			},
 		},
 	}
-	vm.pushFrame(frame)
+	vm.frames[vm.framesIndex] = frame
+	vm.framesIndex++
 }
 
 func (vm *VM) LastPopped() object.Object {
 	return vm.stack[vm.stackIndex]
 }
 
-func (vm *VM) Run() {
+func (vm *VM) Run() error {
 	frame := vm.currentFrame()
 	for frame.InsIndex < len(frame.Instructions()) {
+		frame.OpIndex = frame.InsIndex
 		remain := frame.Instructions()[frame.InsIndex:]
 		op, operands, width := code.Unmake(remain)
 		frame.InsIndex += width // Before run, because of jumps!!
-		vm.run(op, operands)
+		if err := vm.run(op, operands); err != nil {
+			return vm.newRuntimeError(op, err)
+		}
 		frame = vm.currentFrame()
 	}
+	return nil
 }
 
 func (vm *VM) currentFrame() *Frame {
 	return vm.frames[vm.framesIndex-1]
 }
 
-func (vm *VM) getOperand(operands []int) int {
+func newError(format string, a ...any) error {
+	message := "cannot run virtual machine; " + format
+	return fmt.Errorf(message, a...)
+}
+
+func (vm *VM) getOperand(operands []int) (int, error) {
 	if len(operands) == 0 {
-		message := "cannot run virtual machine; " +
-			"unexpected number of operands encountered"
-		log.Fatal(message)
+		return 0, newError("unexpected number of operands encountered")
 	}
-	return operands[0]
+	return operands[0], nil
 }
 
 func (vm *VM) isTruthy(obj object.Object) bool {
@@ -80,20 +99,21 @@ func (vm *VM) isTruthy(obj object.Object) bool {
 	return b.Value
 }
 
-func (vm *VM) run(op code.Opcode, operands []int) {
+func (vm *VM) run(op code.Opcode, operands []int) error {
+	var err error
 	switch op {
 	case code.OpConstant:
-		vm.runOpConstant(operands)
+		err = vm.runOpConstant(operands)
 	case code.OpTrue:
-		vm.runOpTrue()
+		err = vm.runOpTrue()
 	case code.OpFalse:
-		vm.runOpFalse()
+		err = vm.runOpFalse()
 	case code.OpNull:
-		vm.runOpNull()
+		err = vm.runOpNull()
 	case code.OpArray:
-		vm.runOpArray(operands)
+		err = vm.runOpArray(operands)
 	case code.OpHash:
-		vm.runOpHash(operands)
+		err = vm.runOpHash(operands)
 	case code.OpAdd,
 		code.OpSub,
 		code.OpMul,
@@ -101,78 +121,117 @@ func (vm *VM) run(op code.Opcode, operands []int) {
 		code.OpEqual,
 		code.OpNotEqual,
 		code.OpGreaterThan,
-		code.OpLowerThan:
-		vm.runInfixOperation(op)
-	case code.OpBang, code.OpMinus:
-		vm.runPrefixOperation(op)
+		code.OpLowerThan,
+		code.OpMod,
+		code.OpPow,
+		code.OpGreaterEqual,
+		code.OpLowerEqual,
+		code.OpBitAnd,
+		code.OpBitOr,
+		code.OpBitXor,
+		code.OpShiftLeft,
+		code.OpShiftRight:
+		err = vm.runInfixOperation(op)
+	case code.OpBang, code.OpMinus, code.OpBitNot:
+		err = vm.runPrefixOperation(op)
 	case code.OpIndex:
-		vm.runOpIndex()
+		err = vm.runOpIndex()
+	case code.OpIter:
+		err = vm.runOpIter()
 	case code.OpSetGlobal:
-		vm.runOpSetGlobal(operands)
+		err = vm.runOpSetGlobal(operands)
 	case code.OpGetGlobal:
-		vm.runOpGetGlobal(operands)
+		err = vm.runOpGetGlobal(operands)
 	case code.OpSetLocal:
-		vm.runOpSetLocal(operands)
+		err = vm.runOpSetLocal(operands)
 	case code.OpGetLocal:
-		vm.runOpGetLocal(operands)
+		err = vm.runOpGetLocal(operands)
 	case code.OpGetBuiltin:
-		vm.runOpGetBuiltin(operands)
+		err = vm.runOpGetBuiltin(operands)
 	case code.OpGetFree:
-		vm.runOpGetFree(operands)
+		err = vm.runOpGetFree(operands)
+	case code.OpSetFree:
+		err = vm.runOpSetFree(operands)
+	case code.OpSetIndex:
+		err = vm.runOpSetIndex()
+	case code.OpCaptureLocal:
+		err = vm.runOpCaptureLocal(operands)
+	case code.OpCaptureFree:
+		err = vm.runOpCaptureFree(operands)
 	case code.OpCall:
-		vm.runOpCall(operands)
+		err = vm.runOpCall(operands)
 	case code.OpClosure:
-		vm.runOpClosure(operands)
+		err = vm.runOpClosure(operands)
 	case code.OpCurrentClosure:
-		vm.runOpCurrentClosure()
+		err = vm.runOpCurrentClosure()
 	case code.OpReturnValue:
-		vm.runOpReturnValue()
+		err = vm.runOpReturnValue()
 	case code.OpReturn:
-		vm.runOpReturn()
+		err = vm.runOpReturn()
+	case code.OpImport:
+		err = vm.runOpImport(operands)
 	case code.OpJump:
-		vm.runOpJump(operands)
+		err = vm.runOpJump(operands)
 	case code.OpJumpIf:
-		vm.runOpJumpIf(operands)
+		err = vm.runOpJumpIf(operands)
 	case code.OpPop:
 		vm.pop()
+	case code.OpDup:
+		err = vm.push(vm.stack[vm.stackIndex-1])
+	case code.OpQuote:
+		err = vm.runOpQuote(operands)
 	default:
-		message := "cannot run virtual machine; " +
-			"unexpected Opcode encountered"
-		log.Fatal(message)
+		err = newError("unexpected Opcode encountered")
 	}
+	return err
 }
 
-func (vm *VM) runOpConstant(operands []int) {
-	operand := vm.getOperand(operands)
-	constant := vm.constants[operand]
-	vm.push(constant)
+func (vm *VM) runOpConstant(operands []int) error {
+	operand, err := vm.getOperand(operands)
+	if err != nil {
+		return err
+	}
+	if operand >= len(vm.constants) {
+		return newError("constant %v is undefined", operand)
+	}
+	return vm.push(vm.constants[operand])
 }
 
-func (vm *VM) push(obj object.Object) {
+func (vm *VM) push(obj object.Object) error {
 	if vm.stackIndex >= StackSize {
-		message := "cannot run virtual machine; stack overflow"
-		log.Fatal(message)
+		return newError("stack overflow")
 	}
 	vm.stack[vm.stackIndex] = obj
 	vm.stackIndex++
+	return nil
+}
+
+func (vm *VM) pushResult(obj object.Object) error {
+	if err, ok := obj.(*object.Error); ok {
+		return err
+	}
+	return vm.push(obj)
 }
 
-func (vm *VM) runOpTrue() {
-	vm.push(object.TRUE)
+func (vm *VM) runOpTrue() error {
+	return vm.push(object.TRUE)
 }
 
-func (vm *VM) runOpFalse() {
-	vm.push(object.FALSE)
+func (vm *VM) runOpFalse() error {
+	return vm.push(object.FALSE)
 }
 
-func (vm *VM) runOpNull() {
-	vm.push(object.NULL)
+func (vm *VM) runOpNull() error {
+	return vm.push(object.NULL)
 }
 
-func (vm *VM) runOpArray(operands []int) {
-	operand := vm.getOperand(operands)
+func (vm *VM) runOpArray(operands []int) error {
+	operand, err := vm.getOperand(operands)
+	if err != nil {
+		return err
+	}
 	obj := &object.Array{Elements: vm.popNReverse(operand)}
-	vm.push(obj)
+	return vm.push(obj)
 }
 
 func (vm *VM) popNReverse(n int) []object.Object {
@@ -189,31 +248,48 @@ func (vm *VM) popN(n int) []object.Object {
 	return elements
 }
 
-func (vm *VM) runOpHash(operands []int) {
-	operand := vm.getOperand(operands)
-	obj := vm.innerRunOpHash(operand)
-	vm.push(obj)
+func (vm *VM) runOpHash(operands []int) error {
+	operand, err := vm.getOperand(operands)
+	if err != nil {
+		return err
+	}
+	obj, err := vm.innerRunOpHash(operand)
+	if err != nil {
+		return err
+	}
+	return vm.push(obj)
 }
 
-func (vm *VM) innerRunOpHash(operand int) *object.Hash {
+func (vm *VM) innerRunOpHash(operand int) (*object.Hash, error) {
 	pairs := map[object.HashKey]object.HashPair{}
 	for i := 0; i < operand; i++ {
-		v, k := vm.pop(), vm.popHashable()
+		v := vm.pop()
+		k, err := vm.popHashable()
+		if err != nil {
+			return nil, err
+		}
 		pairs[k.HashKey()] = object.HashPair{Key: k, Value: v}
 	}
-	return &object.Hash{Pairs: pairs}
+	return &object.Hash{Pairs: pairs}, nil
 }
 
-func (vm *VM) popHashable() object.Hashable {
+func (vm *VM) popHashable() (object.Hashable, error) {
 	obj := vm.pop()
-	return object.CastToHashable(obj)
+	hash, err := object.CastToHashable(obj)
+	if err != nil {
+		return nil, err
+	}
+	return hash, nil
 }
 
-func (vm *VM) runInfixOperation(op code.Opcode) {
+func (vm *VM) runInfixOperation(op code.Opcode) error {
 	right, left := vm.pop(), vm.pop()
-	operator := vm.getInfixOperator(op)
+	operator, err := vm.getInfixOperator(op)
+	if err != nil {
+		return err
+	}
 	obj := evaluator.EvalInfix(left, operator, right)
-	vm.push(obj)
+	return vm.pushResult(obj)
 }
 
 func (vm *VM) pop() object.Object {
@@ -221,178 +297,339 @@ func (vm *VM) pop() object.Object {
 	return vm.LastPopped()
 }
 
-func (vm *VM) getInfixOperator(op code.Opcode) string {
+func (vm *VM) getInfixOperator(op code.Opcode) (string, error) {
 	operator, ok := code.InfixOperatorReverse[op]
 	if !ok {
-		message := "cannot run virtual machine; " +
-			"unexpected Opcode encountered has infix operator"
-		log.Fatal(message)
+		message := "unexpected Opcode encountered has infix operator"
+		return "", newError(message)
 	}
-	return operator
+	return operator, nil
 }
 
-func (vm *VM) runPrefixOperation(op code.Opcode) {
+func (vm *VM) runPrefixOperation(op code.Opcode) error {
 	right := vm.pop()
-	operator := vm.getPrefixOperator(op)
+	operator, err := vm.getPrefixOperator(op)
+	if err != nil {
+		return err
+	}
 	obj := evaluator.EvalPrefix(operator, right)
-	vm.push(obj)
+	return vm.pushResult(obj)
 }
 
-func (vm *VM) getPrefixOperator(op code.Opcode) string {
+func (vm *VM) getPrefixOperator(op code.Opcode) (string, error) {
 	operator, ok := code.PrefixOperatorReverse[op]
 	if !ok {
-		message := "cannot run virtual machine; " +
-			"unexpected Opcode encountered has prefix operator"
-		log.Fatal(message)
+		message := "unexpected Opcode encountered has prefix operator"
+		return "", newError(message)
 	}
-	return operator
+	return operator, nil
 }
 
-func (vm *VM) runOpIndex() {
+func (vm *VM) runOpIndex() error {
 	index, left := vm.pop(), vm.pop()
 	obj := evaluator.EvalIndex(left, index)
-	vm.push(obj)
+	return vm.pushResult(obj)
 }
 
-func (vm *VM) runOpSetGlobal(operands []int) {
-	operand := vm.getOperand(operands)
-	if operand > GlobalsSize {
-		message := "cannot run virtual machine; globals overflow"
-		log.Fatal(message)
+func (vm *VM) runOpIter() error {
+	obj := vm.pop()
+	elements, err := evaluator.EvalIterable(obj)
+	if err != nil {
+		return err
+	}
+	return vm.push(&object.Array{Elements: elements})
+}
+
+func (vm *VM) runOpSetGlobal(operands []int) error {
+	operand, err := vm.getOperand(operands)
+	if err != nil {
+		return err
+	}
+	if operand >= len(vm.globals) {
+		return newError("globals overflow")
 	}
 	vm.globals[operand] = vm.pop()
+	return nil
 }
 
-func (vm *VM) runOpGetGlobal(operands []int) {
-	operand := vm.getOperand(operands)
-	vm.push(vm.globals[operand])
+func (vm *VM) runOpGetGlobal(operands []int) error {
+	operand, err := vm.getOperand(operands)
+	if err != nil {
+		return err
+	}
+	if operand >= len(vm.globals) {
+		return newError("globals overflow")
+	}
+	if vm.globals[operand] == nil {
+		return newError("global %v is undefined", operand)
+	}
+	return vm.push(vm.globals[operand])
 }
 
-func (vm *VM) runOpSetLocal(operands []int) {
-	operand := vm.getOperand(operands)
+func (vm *VM) runOpSetLocal(operands []int) error {
+	operand, err := vm.getOperand(operands)
+	if err != nil {
+		return err
+	}
 	obj := vm.pop()
 	vm.setLocal(obj, operand)
+	return nil
 }
 
 func (vm *VM) setLocal(obj object.Object, operand int) {
 	frame := vm.currentFrame()
-	vm.stack[frame.BaseStackIndex+operand] = obj
+	index := frame.BaseStackIndex + operand
+	if cell, ok := vm.stack[index].(*object.Cell); ok {
+		cell.Value = obj
+	} else {
+		vm.stack[index] = obj
+	}
 }
 
-func (vm *VM) runOpGetLocal(operands []int) {
-	operand := vm.getOperand(operands)
+func (vm *VM) runOpGetLocal(operands []int) error {
+	operand, err := vm.getOperand(operands)
+	if err != nil {
+		return err
+	}
 	obj := vm.getLocal(operand)
-	vm.push(obj)
+	return vm.push(obj)
 }
 
 func (vm *VM) getLocal(operand int) object.Object {
 	frame := vm.currentFrame()
-	return vm.stack[frame.BaseStackIndex+operand]
+	return deref(vm.stack[frame.BaseStackIndex+operand])
 }
 
-func (vm *VM) runOpGetBuiltin(operands []int) {
-	operand := vm.getOperand(operands)
-	b := object.Builtins[operand]
-	vm.push(b.Builtin)
+func deref(obj object.Object) object.Object {
+	if cell, ok := obj.(*object.Cell); ok {
+		return cell.Value
+	}
+	return obj
 }
 
-func (vm *VM) runOpGetFree(operands []int) {
-	operand := vm.getOperand(operands)
+func (vm *VM) runOpGetBuiltin(operands []int) error {
+	operand, err := vm.getOperand(operands)
+	if err != nil {
+		return err
+	}
+	if operand >= len(vm.builtins) {
+		return newError("built-in %v is undefined", operand)
+	}
+	return vm.push(vm.builtins[operand])
+}
+
+func (vm *VM) runOpGetFree(operands []int) error {
+	cell, err := vm.getFree(operands)
+	if err != nil {
+		return err
+	}
+	return vm.push(cell.Value)
+}
+
+func (vm *VM) getFree(operands []int) (*object.Cell, error) {
+	operand, err := vm.getOperand(operands)
+	if err != nil {
+		return nil, err
+	}
 	frame := vm.currentFrame()
-	obj := frame.Closure.Free[operand]
-	vm.push(obj)
+	if operand >= len(frame.Closure.Free) {
+		return nil, newError("free variable %v is undefined", operand)
+	}
+	cell, ok := frame.Closure.Free[operand].(*object.Cell)
+	if !ok {
+		return nil, newError("free variable %v is not captured", operand)
+	}
+	return cell, nil
 }
 
-func (vm *VM) runOpCall(operands []int) {
-	operand := vm.getOperand(operands)
+func (vm *VM) runOpSetFree(operands []int) error {
+	cell, err := vm.getFree(operands)
+	if err != nil {
+		return err
+	}
+	cell.Value = vm.pop()
+	return nil
+}
+
+func (vm *VM) runOpSetIndex() error {
+	value, index, left := vm.pop(), vm.pop(), vm.pop()
+	obj := evaluator.EvalSetIndex(left, index, value)
+	return vm.pushResult(obj)
+}
+
+func (vm *VM) runOpCaptureLocal(operands []int) error {
+	operand, err := vm.getOperand(operands)
+	if err != nil {
+		return err
+	}
+	frame := vm.currentFrame()
+	index := frame.BaseStackIndex + operand
+	cell, ok := vm.stack[index].(*object.Cell)
+	if !ok {
+		cell = &object.Cell{Value: vm.stack[index]}
+		vm.stack[index] = cell
+	}
+	return vm.push(cell)
+}
+
+func (vm *VM) runOpCaptureFree(operands []int) error {
+	cell, err := vm.getFree(operands)
+	if err != nil {
+		return err
+	}
+	return vm.push(cell)
+}
+
+func (vm *VM) runOpCall(operands []int) error {
+	operand, err := vm.getOperand(operands)
+	if err != nil {
+		return err
+	}
 	fn := vm.getFunction(operand)
-	vm.dispatchCall(fn, operand)
+	return vm.dispatchCall(fn, operand)
 }
 
 func (vm *VM) getFunction(operand int) object.Object {
 	return vm.stack[vm.stackIndex-operand-1]
 }
 
-func (vm *VM) dispatchCall(fn object.Object, operand int) {
+func (vm *VM) dispatchCall(fn object.Object, operand int) error {
+	var err error
 	switch f := fn.(type) {
 	case *object.Closure:
-		vm.runClosure(f, operand)
+		err = vm.runClosure(f, operand)
 	case *object.Builtin:
-		vm.runBuiltinFunction(f, operand)
+		err = vm.runBuiltinFunction(f, operand)
 	default:
-		message := "cannot run virtual machine; " +
-			"unexpected object encountered has function in function call"
-		log.Fatal(message)
+		message := "unexpected object %v encountered " +
+			"has function in function call"
+		err = newError(message, object.TypeOf(fn))
 	}
+	return err
 }
 
-func (vm *VM) runClosure(closure *object.Closure, operand int) {
-	vm.validateArguments(closure, operand)
+func (vm *VM) runClosure(closure *object.Closure, operand int) error {
+	if err := vm.validateArguments(closure, operand); err != nil {
+		return err
+	}
 	frame := &Frame{Closure: closure, BaseStackIndex: vm.stackIndex - operand}
-	vm.pushFrame(frame)
+	return vm.pushFrame(frame)
 }
 
-func (vm *VM) validateArguments(closure *object.Closure, operand int) {
+func (vm *VM) validateArguments(closure *object.Closure, operand int) error {
 	if closure.Fn.NumParameters != operand {
-		message := "cannot run virtual machine; " +
-			"unexpected number of arguments in call to function"
-		log.Fatal(message)
+		message := "unexpected number of arguments in call to function; " +
+			"got=%v, expected=%v"
+		return newError(message, operand, closure.Fn.NumParameters)
 	}
+	return nil
 }
 
-func (vm *VM) pushFrame(frame *Frame) {
+func (vm *VM) pushFrame(frame *Frame) error {
+	if vm.framesIndex >= FramesSize {
+		return newError("stack overflow")
+	}
+	if vm.stackIndex+frame.NumLocals() > StackSize {
+		return newError("stack overflow")
+	}
 	vm.frames[vm.framesIndex] = frame
 	vm.framesIndex++
+	vm.clearLocals(frame)
 	vm.stackIndex += frame.NumLocals()
+	return nil
 }
 
-func (vm *VM) runBuiltinFunction(fn *object.Builtin, operand int) {
+func (vm *VM) clearLocals(frame *Frame) {
+	parameters := min(frame.Closure.Fn.NumParameters, frame.NumLocals())
+	from := frame.BaseStackIndex + parameters
+	clear(vm.stack[from : frame.BaseStackIndex+frame.NumLocals()])
+}
+
+func (vm *VM) runBuiltinFunction(fn *object.Builtin, operand int) error {
 	arguments := vm.popNReverse(operand)
 	vm.pop() // Pop builtin!
 	obj := fn.Fn(arguments...)
-	vm.push(obj)
+	return vm.pushResult(obj)
+}
+
+func (vm *VM) runOpClosure(operands []int) error {
+	index, count, err := vm.getTwoOperands(operands)
+	if err != nil {
+		return err
+	}
+	obj, err := vm.getClosure(index, count)
+	if err != nil {
+		return err
+	}
+	return vm.push(obj)
 }
 
-func (vm *VM) runOpClosure(operands []int) {
-	index, count := vm.getTwoOperands(operands)
-	obj := vm.getClosure(index, count)
-	vm.push(obj)
+func (vm *VM) runOpQuote(operands []int) error {
+	index, count, err := vm.getTwoOperands(operands)
+	if err != nil {
+		return err
+	}
+	if index >= len(vm.constants) {
+		return newError("constant %v is undefined", index)
+	}
+	quote, ok := vm.constants[index].(*object.Quote)
+	if !ok {
+		return newError("unexpected constant when expecting quote")
+	}
+	values := vm.popNReverse(count)
+	obj := evaluator.EvalUnquote(quote, values)
+	return vm.pushResult(obj)
 }
 
-func (vm *VM) getTwoOperands(operands []int) (int, int) {
+func (vm *VM) getTwoOperands(operands []int) (int, int, error) {
 	if len(operands) < 2 {
-		message := "cannot run virtual machine; " +
-			"unexpected number of operands encountered"
-		log.Fatal(message)
+		message := "unexpected number of operands encountered"
+		return 0, 0, newError(message)
 	}
-	return operands[0], operands[1]
+	return operands[0], operands[1], nil
 }
 
-func (vm *VM) getClosure(index int, count int) *object.Closure {
-	obj := vm.getCompiledFunction(index)
+func (vm *VM) getClosure(index int, count int) (*object.Closure, error) {
+	obj, err := vm.getCompiledFunction(index)
+	if err != nil {
+		return nil, err
+	}
 	free := vm.popNReverse(count)
-	return &object.Closure{Fn: obj, Free: free}
+	return &object.Closure{Fn: obj, Free: free}, nil
 }
 
-func (vm *VM) getCompiledFunction(index int) *object.CompiledFunction {
+func (vm *VM) getCompiledFunction(
+	index int,
+) (*object.CompiledFunction, error) {
+	if index >= len(vm.constants) {
+		return nil, newError("constant %v is undefined", index)
+	}
 	obj, ok := vm.constants[index].(*object.CompiledFunction)
 	if !ok {
-		message := "cannot run virtual machine; " +
-			"unexpected constant when expecting function"
-		log.Fatal(message)
+		message := "unexpected constant when expecting function"
+		return nil, newError(message)
 	}
-	return obj
+	return obj, nil
 }
 
-func (vm *VM) runOpCurrentClosure() {
+func (vm *VM) runOpCurrentClosure() error {
 	frame := vm.currentFrame()
-	vm.push(frame.Closure)
+	return vm.push(frame.Closure)
 }
 
-func (vm *VM) runOpReturnValue() {
+func (vm *VM) runOpReturnValue() error {
 	obj := vm.pop()
+	if vm.framesIndex == 1 {
+		vm.halt()
+		return nil
+	}
 	vm.popFrame()
-	vm.push(obj)
+	return vm.push(obj)
+}
+
+func (vm *VM) halt() {
+	frame := vm.currentFrame()
+	frame.InsIndex = len(frame.Instructions())
 }
 
 func (vm *VM) popFrame() {
@@ -401,19 +638,44 @@ func (vm *VM) popFrame() {
 	vm.framesIndex--
 }
 
-func (vm *VM) runOpReturn() {
+func (vm *VM) runOpReturn() error {
+	if vm.framesIndex == 1 {
+		vm.halt()
+		return nil
+	}
 	vm.popFrame()
-	vm.push(object.NULL)
+	return vm.push(object.NULL)
 }
 
-func (vm *VM) runOpJump(operands []int) {
+func (vm *VM) runOpJump(operands []int) error {
+	operand, err := vm.getOperand(operands)
+	if err != nil {
+		return err
+	}
 	frame := vm.currentFrame()
-	frame.InsIndex = vm.getOperand(operands)
+	frame.InsIndex = operand
+	return nil
+}
+
+func (vm *VM) runOpImport(operands []int) error {
+	global, target, err := vm.getTwoOperands(operands)
+	if err != nil {
+		return err
+	}
+	if global >= len(vm.globals) {
+		return newError("globals overflow")
+	}
+	if namespace := vm.globals[global]; namespace != nil {
+		vm.currentFrame().InsIndex = target
+		return vm.push(namespace)
+	}
+	return nil
 }
 
-func (vm *VM) runOpJumpIf(operands []int) {
+func (vm *VM) runOpJumpIf(operands []int) error {
 	obj := vm.pop()
 	if !vm.isTruthy(obj) {
-		vm.runOpJump(operands)
+		return vm.runOpJump(operands)
 	}
+	return nil
 }
@@ -1,13 +1,20 @@
 package vm
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/vincentlabelle/monkey/ast"
+	"github.com/vincentlabelle/monkey/code"
 	"github.com/vincentlabelle/monkey/compiler"
+	"github.com/vincentlabelle/monkey/evaluator"
 	"github.com/vincentlabelle/monkey/lexer"
+	"github.com/vincentlabelle/monkey/module"
 	"github.com/vincentlabelle/monkey/object"
 	"github.com/vincentlabelle/monkey/parser"
+	"github.com/vincentlabelle/monkey/token"
 )
 
 func Test(t *testing.T) {
@@ -27,6 +34,17 @@ func Test(t *testing.T) {
 		{`5 * 2 + 10;`, &object.Integer{Value: 20}},
 		{`5 + 2 * 10;`, &object.Integer{Value: 25}},
 		{`5 * (2 + 10);`, &object.Integer{Value: 60}},
+		{`2.5;`, &object.Float{Value: 2.5}},
+		{`-1.5e2;`, &object.Float{Value: -150}},
+		{`1.5 + 1.5;`, &object.Float{Value: 3}},
+		{`1 + 0.5;`, &object.Float{Value: 1.5}},
+		{`0.5 * 4;`, &object.Float{Value: 2}},
+		{`7 / 2.0;`, &object.Float{Value: 3.5}},
+		{`1 - 2.5e-1;`, &object.Float{Value: 0.75}},
+		{`1 == 1.0;`, object.TRUE},
+		{`0.1 + 0.2 != 0.3;`, object.TRUE},
+		{`2 < 2.5;`, object.TRUE},
+		{`{1.5: "a"}[1.5];`, &object.String{Value: "a"}},
 		{`true;`, object.TRUE},
 		{`false;`, object.FALSE},
 		{`1 < 2;`, object.TRUE},
@@ -278,6 +296,8 @@ func Test(t *testing.T) {
 		},
 		{`len("")`, &object.Integer{Value: 0}},
 		{`len("four")`, &object.Integer{Value: 4}},
+		{`len("h\u{e9}llo")`, &object.Integer{Value: 5}},
+		{"`a\\n` + \"\\t\\\"\";", &object.String{Value: "a\\n\t\""}},
 		{`len("hello world")`, &object.Integer{Value: 11}},
 		{`len([1, 2, 3])`, &object.Integer{Value: 3}},
 		{`len([])`, &object.Integer{Value: 0}},
@@ -417,31 +437,604 @@ func Test(t *testing.T) {
 			`,
 			&object.Integer{Value: 0},
 		},
+		{
+			`
+			let i = 0;
+			let sum = 0;
+			while (i < 10) {
+				let i = i + 1;
+				if (i == 3) { continue; }
+				if (i > 6) { break; }
+				let sum = sum + i;
+			}
+			sum;
+			`,
+			&object.Integer{Value: 18},
+		},
+		{
+			`
+			let sum = fn(xs) {
+				let total = 0;
+				for (x in xs) { let total = total + x; }
+				total;
+			};
+			sum([1, 2, 3]);
+			`,
+			&object.Integer{Value: 6},
+		},
+		{
+			`
+			let keys = [];
+			for (k in {"b": 1, "a": 2, 3: 4}) { let keys = push(keys, k); }
+			keys;
+			`,
+			&object.Array{Elements: []object.Object{
+				&object.Integer{Value: 3},
+				&object.String{Value: "a"},
+				&object.String{Value: "b"},
+			}},
+		},
+		{
+			`
+			let chars = [];
+			for (c in "h\u{e9}") {
+				for (n in [1, 2]) {
+					if (n == 2) { break; }
+					let chars = push(chars, c);
+				}
+			}
+			chars;
+			`,
+			&object.Array{Elements: []object.Object{
+				&object.String{Value: "h"},
+				&object.String{Value: "\u00e9"},
+			}},
+		},
+		{
+			`
+			let s = 0;
+			for (x in [1, 2]) {
+				for (y in [10, 20]) { let s = s + x * y; }
+			}
+			for (x in [100]) { let s = s + x; }
+			s;
+			`,
+			&object.Integer{Value: 190},
+		},
+		{`fn() { while (true) { return 7; } }();`, &object.Integer{Value: 7}},
+		{`fn() { let x = 1; }();`, object.NULL},
+		{`if (true) { let y = 2; };`, object.NULL},
+		{
+			`let n = 0; while (n < 5000) { let n = n + 1; } n;`,
+			&object.Integer{Value: 5000},
+		},
+		{`7 % 3;`, &object.Integer{Value: 1}},
+		{`-7 % 3;`, &object.Integer{Value: -1}},
+		{`7.5 % 2;`, &object.Float{Value: 1.5}},
+		{`2 ** 10;`, &object.Integer{Value: 1024}},
+		{`2 ** 3 ** 2;`, &object.Integer{Value: 512}},
+		{`-2 ** 2;`, &object.Integer{Value: -4}},
+		{`2 ** -1;`, &object.Float{Value: 0.5}},
+		{`4 ** 0.5;`, &object.Float{Value: 2}},
+		{`1 <= 1;`, object.TRUE},
+		{`2 <= 1;`, object.FALSE},
+		{`1 >= 1.5;`, object.FALSE},
+		{`6 & 3;`, &object.Integer{Value: 2}},
+		{`6 | 3;`, &object.Integer{Value: 7}},
+		{`6 ^ 3;`, &object.Integer{Value: 5}},
+		{`1 << 4;`, &object.Integer{Value: 16}},
+		{`-16 >> 2;`, &object.Integer{Value: -4}},
+		{`~5;`, &object.Integer{Value: -6}},
+		{`1 + 1 << 2 | 1;`, &object.Integer{Value: 9}},
+		{`true && false;`, object.FALSE},
+		{`1 && "a";`, object.TRUE},
+		{`false || 0;`, object.TRUE},
+		{`if (false) { 1 } || false;`, object.FALSE},
+		{`1 < 2 && 2 < 3 || false;`, object.TRUE},
+		{`false && 1();`, object.FALSE},
+		{`true || 1();`, object.TRUE},
+		{
+			`let x = 0; false && (x = 1); true || (x = 2); x;`,
+			&object.Integer{Value: 0},
+		},
+		{`let x = 1; x = x + 1; x;`, &object.Integer{Value: 2}},
+		{`let x = 1; let y = x = 5; x + y;`, &object.Integer{Value: 10}},
+		{
+			`let a = [1, 2, 3]; a[1] = 5; a;`,
+			&object.Array{Elements: []object.Object{
+				&object.Integer{Value: 1},
+				&object.Integer{Value: 5},
+				&object.Integer{Value: 3},
+			}},
+		},
+		{
+			`let h = {"a": 1}; h["b"] = 2; h["a"] = 3; [h["a"], h["b"]];`,
+			&object.Array{Elements: []object.Object{
+				&object.Integer{Value: 3},
+				&object.Integer{Value: 2},
+			}},
+		},
+		{
+			`let g = 0; let f = fn() { g = g + 1; }; f(); f(); g;`,
+			&object.Integer{Value: 2},
+		},
+		{
+			`
+			let counter = fn() { let n = 0; fn() { n = n + 1; } };
+			let c = counter();
+			c();
+			c();
+			c();
+			`,
+			&object.Integer{Value: 3},
+		},
+		{
+			`
+			let f = fn() {
+				let n = 1;
+				let g = fn() { fn() { n = n * 10; } };
+				g()();
+				g()();
+				n;
+			};
+			f();
+			`,
+			&object.Integer{Value: 100},
+		},
+		{
+			`let f = fn(x) { let g = fn() { x = x + 1; }; g(); x; }; f(1);`,
+			&object.Integer{Value: 2},
+		},
+		{
+			`
+			let f = fn(a) { let b = a; let g = fn() { b }; b = b + 1; g(); };
+			f(1) + f(10);
+			`,
+			&object.Integer{Value: 13},
+		},
+		{
+			`
+			fn() {
+				let fs = [];
+				for (i in [1, 2, 3]) { let fs = push(fs, fn() { i }); }
+				fs[0]();
+			}();
+			`,
+			&object.Integer{Value: 3},
+		},
 	}
 
 	for _, s := range setup {
-		vm := new_(s.input)
-		vm.Run()
+		vm := new_(t, s.input)
+		if err := vm.Run(); err != nil {
+			t.Fatalf("unexpected error. got=%v", err)
+		}
 		actual := vm.LastPopped()
 		testObject(t, actual, s.expected)
 	}
 }
 
-func new_(input string) *VM {
-	code := compile(input)
+func TestErrors(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`5 + true;`,
+			"1:1: cannot evaluate program; " +
+				"operands Integer and Boolean with operator + " +
+				"aren't of the same type (at OpAdd)" +
+				"\n\tat <main> (1:1)",
+		},
+		{
+			"let f = fn(x) { x; };\nf(1, 2);",
+			"2:1: cannot run virtual machine; " +
+				"unexpected number of arguments in call to function; " +
+				"got=2, expected=1 (at OpCall)" +
+				"\n\tat <main> (2:1)",
+		},
+		{
+			"let f = fn() { 1(); };\nlet g = fn() { f(); };\ng();",
+			"1:16: cannot run virtual machine; " +
+				"unexpected object Integer encountered " +
+				"has function in function call (at OpCall)" +
+				"\n\tat f (1:16)" +
+				"\n\tat g (2:16)" +
+				"\n\tat <main> (3:1)",
+		},
+		{
+			`fn(x) { x % 0; }(1);`,
+			"1:9: cannot evaluate program; " +
+				"division by zero with operator % (at OpMod)" +
+				"\n\tat <anonymous> (1:9)" +
+				"\n\tat <main> (1:1)",
+		},
+		{
+			"let a = [1];\na[3] = 2;",
+			"2:1: cannot evaluate program; " +
+				"index 3 out of range in index assignment (at OpSetIndex)" +
+				"\n\tat <main> (2:1)",
+		},
+		{
+			`fn() { len(1); }();`,
+			"1:8: cannot call built-in; invalid argument Integer (at OpCall)" +
+				"\n\tat <anonymous> (1:8)" +
+				"\n\tat <main> (1:1)",
+		},
+		{
+			`quote(unquote(len));`,
+			"1:1: cannot evaluate program; cannot unquote Builtin (at OpQuote)" +
+				"\n\tat <main> (1:1)",
+		},
+	}
+
+	for _, s := range setup {
+		vm := new_(t, s.input)
+		err := vm.Run()
+		if err == nil {
+			t.Fatalf("expected error. expected=%q", s.expected)
+		}
+		if err.Error() != s.expected {
+			t.Fatalf(
+				"error mismatch. got=%q, expected=%q",
+				err.Error(),
+				s.expected,
+			)
+		}
+	}
+}
+
+var modules = fstest.MapFS{
+	"lib/counter.mk": {Data: []byte(`
+		let count = 0;
+		export let next = fn() { count = count + 1; count };
+		export let name = "counter";
+	`)},
+	"lib/twice.mk": {Data: []byte(`
+		let counter = import "counter.mk";
+		export let next = fn() { counter.next(); counter.next() };
+	`)},
+	"a.mk":      {Data: []byte(`export let b = import "./b.mk";`)},
+	"b.mk":      {Data: []byte(`export let a = import "./a.mk";`)},
+	"broken.mk": {Data: []byte("let f = fn() { 1 + true };\nf();")},
+}
+
+func TestImport(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected object.Object
+	}{
+		{
+			`let c = import "counter.mk"; c.name;`,
+			object.NativeToString("counter"),
+		},
+		{
+			`let c = import "counter.mk"; c.next(); c.next();`,
+			&object.Integer{Value: 2},
+		},
+		{
+			`let c = import "counter.mk"; c["count"];`,
+			object.NULL,
+		},
+		{
+			`let c = import "counter.mk";
+			let d = import "./lib/counter.mk";
+			c.next();
+			d.next();`,
+			&object.Integer{Value: 2},
+		},
+		{
+			`let t = import "twice.mk";
+			let c = import "counter.mk";
+			t.next();
+			c.next();`,
+			&object.Integer{Value: 3},
+		},
+		{
+			`let get = fn() { import "counter.mk" };
+			get().next();
+			get().next();`,
+			&object.Integer{Value: 2},
+		},
+	}
+
+	for _, s := range setup {
+		vm := New(compileMain(t, s.input))
+		if err := vm.Run(); err != nil {
+			t.Fatalf("unexpected error. got=%v", err)
+		}
+		testObject(t, vm.LastPopped(), s.expected)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	setup := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`import "missing.mk";`,
+			`/main.mk:1:1: cannot compile; ` +
+				`cannot import; cannot find module "missing.mk"`,
+		},
+		{
+			`import "./a.mk";`,
+			"/b.mk:1:16: cannot compile; " +
+				"import cycle /a.mk -> /b.mk -> /a.mk",
+		},
+	}
+
+	for _, s := range setup {
+		lex := lexer.NewFile("/main.mk", s.input)
+		program, _ := parser.New(lex).ParseProgram()
+		c := compiler.New()
+		c.SetLoader(module.NewLoader(modules, "/lib"))
+		_, err := c.Compile(program)
+		if err == nil || err.Error() != s.expected {
+			t.Fatalf("error mismatch. got=%v, expected=%q", err, s.expected)
+		}
+	}
+}
+
+func TestImportTrace(t *testing.T) {
+	vm := New(compileMain(t, "let b = import \"./broken.mk\";"))
+	err := vm.Run()
+	expected := "/broken.mk:1:16: cannot evaluate program; " +
+		"operands Integer and Boolean with operator + " +
+		"aren't of the same type (at OpAdd)" +
+		"\n\tat f (/broken.mk:1:16)" +
+		"\n\tat <module> (/broken.mk:2:1)" +
+		"\n\tat <main> (/main.mk:1:9)"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("error mismatch. got=%v, expected=%q", err, expected)
+	}
+}
+
+func compileMain(t *testing.T, input string) *compiler.Bytecode {
+	lex := lexer.NewFile("/main.mk", input)
+	program, _ := parser.New(lex).ParseProgram()
+	c := compiler.New()
+	c.SetLoader(module.NewLoader(modules, "/lib"))
+	bytecode, err := c.Compile(program)
+	if err == nil {
+		err = Verify(bytecode)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	return bytecode
+}
+
+func TestArguments(t *testing.T) {
+	bytecode := compile(t, `args();`)
+	first, second := New(bytecode), New(bytecode)
+	first.SetArguments([]string{"a", "b"})
+	for _, vm := range []*VM{first, second} {
+		if err := vm.Run(); err != nil {
+			t.Fatalf("unexpected error. got=%v", err)
+		}
+	}
+	expected := &object.Array{Elements: []object.Object{
+		object.NativeToString("a"),
+		object.NativeToString("b"),
+	}}
+	testObject(t, first.LastPopped(), expected)
+	testObject(t, second.LastPopped(), &object.Array{})
+}
+
+func TestExcessParameters(t *testing.T) {
+	bytecode := &compiler.Bytecode{
+		Instructions: concatenate(
+			code.Make(code.OpClosure, 0, 0),
+			code.Make(code.OpConstant, 1),
+			code.Make(code.OpConstant, 1),
+			code.Make(code.OpCall, 2),
+			code.Make(code.OpPop),
+		),
+		Constants: []object.Object{
+			&object.CompiledFunction{
+				Instructions: concatenate(
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpReturnValue),
+				),
+				NumLocals:     1,
+				NumParameters: 2,
+			},
+			&object.Integer{Value: 1},
+		},
+	}
+	vm := New(bytecode)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	testObject(t, vm.LastPopped(), &object.Integer{Value: 1})
+}
+
+func TestStackOverflow(t *testing.T) {
+	input := "let f = fn(x) { f(x + 1); };\nf(0);"
+	vm := new_(t, input)
+	err := vm.Run()
+	actual, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("error type mismatch. got=%T, expected=*RuntimeError", err)
+	}
+	if actual.Message != "cannot run virtual machine; stack overflow" {
+		t.Fatalf("message mismatch. got=%q", actual.Message)
+	}
+	if len(actual.Trace) <= traceSize {
+		t.Fatalf("trace length mismatch. got=%v", len(actual.Trace))
+	}
+	expected := TraceEntry{
+		Function: "<main>",
+		Pos:      token.Position{Line: 2, Column: 1, Offset: 29},
+	}
+	if last := actual.Trace[len(actual.Trace)-1]; last != expected {
+		t.Fatalf("entry mismatch. got=%v, expected=%v", last, expected)
+	}
+	if lines := strings.Count(err.Error(), "\n"); lines != traceSize+1 {
+		t.Fatalf(
+			"number of lines mismatch. got=%v, expected=%v",
+			lines,
+			traceSize+1,
+		)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	inputs := []string{
+		`if (true) { 1 } else { 2 };`,
+		`if (1 > 2) { 1 };`,
+		`let x = 0; while (true) { x = x + 1; if (x > 3) { break; } }; x;`,
+		`let x = 0; while (x < 5) { x = x + 1; if (x > 2) { continue; } }; x;`,
+		`let s = 0; for (x in [1, 2, 3]) { if (false) { break; } s = s + x; }; s;`,
+		`let f = fn(n) { if (n < 2) { return n; } f(n - 1) + f(n - 2) }; f(10);`,
+		`let f = fn(x) { return x; x + 1; }; f(1);`,
+		`let f = fn(x) { if (x) { 1 } else { 2 } }; [f(true), f(false)];`,
+		`let f = fn() { let x = 1; x = x + 1; x }; f();`,
+		`let f = fn() { let x = 1; let g = fn() { x = x * 3 }; g() + x }; f();`,
+		`let a = 1; let b = a = 2; [a, b, true && (false || a == 2)];`,
+		`let x = 1; if (x) { if (x) { "a" } else { "b" } } else { "c" };`,
+		`let h = {"a": 1}; h["a"] = h["a"] + 1; h;`,
+		`let s = 0; for (x in [1, 2, 3, 4]) { if (x > 1) { if (x == 2) {
+			continue; } else { if (x == 4) { break; } } } s = s + x; }; s;`,
+		`let f = fn(n) { if (n == 0) { 0 } else { n + f(n - 1) } }; f(300);`,
+		`let f = fn() { f = 1; 2 }; [f(), f];`,
+		`let f = fn(n) { if (n > 0) { f(n - 1) } else { f = n; 3 } }; [f(2), f];`,
+		`let g = fn() { let f = fn() { fn() { f = 3 }(); 4 }; [f(), f] }; g();`,
+		`let f = fn(f) { f = f + 1; f }; let g = fn() { let g = 1; g = 2 };
+			[f(1), g()];`,
+	}
+
+	for _, input := range inputs {
+		testLevels(t, input)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	inputs := []string{
+		`quote(1 + 2);`,
+		`quote(unquote(4 + 4));`,
+		`let x = 8; quote(unquote(x) * 2);`,
+		`let q = quote(4 + 4); quote(unquote(q) * 2);`,
+		`quote(unquote(-1) ** 2);`,
+		`quote(unquote(1.5 < 1) && unquote("a"));`,
+		`let f = fn(x) { quote(unquote(x) + 1) }; [f(1), f(2)];`,
+		`let x = 1; quote(fn(x) { {"a": unquote(x), "b": unquote(x + 1)} });`,
+	}
+
+	for _, input := range inputs {
+		testLevels(t, input)
+	}
+}
+
+func TestWideOperands(t *testing.T) {
+	args := sequence("%v", 300, ", ")
+	params := strings.Map(letters, sequence("x%v", 300, ", ")) // x0 is xa
+	body := sequence("%v;", 20000, " ")
+	inputs := []string{
+		sequence("%v;", 65537, " "),
+		"let c = true; if (c) { " + body + " 1 } else { 2 };",
+		"let x = 0; while (x < 2) { x = x + 1; " + body + " }; x;",
+		"let f = fn(" + params + ") { xcjj }; f(" + args + ");",
+		"let f = fn(" + params + ") { fn() { [" + params + "] } };" +
+			"f(" + args + ")();",
+	}
+
+	for _, input := range inputs {
+		testLevels(t, input)
+	}
+}
+
+func TestGlobalsLimit(t *testing.T) {
+	input := strings.Map(letters, sequence("let g%v = true;", 65536, " "))
+	vm := New(compile(t, input+" gfffff;"))
+	if err := vm.Run(); err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	testObject(t, vm.LastPopped(), object.TRUE)
+
+	input += "\nlet x = 1;"
+	_, err := compiler.New().Compile(parse(input))
+	expected := "2:1: cannot compile; too many globals; limit is 65536"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("error mismatch. got=%v, expected=%q", err, expected)
+	}
+}
+
+func testLevels(t *testing.T, input string) {
+	expected, err := evaluator.Eval(parse(input), object.NewEnvironment())
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	for _, level := range []compiler.Level{
+		compiler.None,
+		compiler.Default,
+		compiler.Aggressive,
+	} {
+		c := compiler.New()
+		c.SetLevel(level)
+		bytecode, err := c.Compile(parse(input))
+		if err == nil {
+			err = Verify(bytecode)
+		}
+		if err != nil {
+			t.Fatalf("unexpected error. got=%v", err)
+		}
+		vm := New(bytecode)
+		if err := vm.Run(); err != nil {
+			t.Fatalf("unexpected error. got=%v", err)
+		}
+		actual := vm.LastPopped()
+		if actual.Inspect() != expected.Inspect() {
+			t.Fatalf(
+				"result mismatch for %q at level %v. got=%v, expected=%v",
+				input,
+				level,
+				actual.Inspect(),
+				expected.Inspect(),
+			)
+		}
+	}
+}
+
+func letters(r rune) rune {
+	if r >= '0' && r <= '9' {
+		return 'a' + r - '0'
+	}
+	return r
+}
+
+func sequence(format string, count int, separator string) string {
+	pieces := make([]string, count)
+	for i := range pieces {
+		pieces[i] = fmt.Sprintf(format, i)
+	}
+	return strings.Join(pieces, separator)
+}
+
+func new_(t *testing.T, input string) *VM {
+	code := compile(t, input)
 	return New(code)
 }
 
-func compile(input string) *compiler.Bytecode {
+func compile(t *testing.T, input string) *compiler.Bytecode {
 	program := parse(input)
 	c := compiler.New()
-	return c.Compile(program)
+	bytecode, err := c.Compile(program)
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	if err := Verify(bytecode); err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	return bytecode
 }
 
 func parse(input string) *ast.Program {
 	lex := lexer.New(input)
 	p := parser.New(lex)
-	return p.ParseProgram()
+	program, _ := p.ParseProgram()
+	return program
 }
 
 func testObject(
@@ -460,6 +1053,16 @@ func testObject(
 			)
 		}
 		testIntegerObject(t, a, e)
+	case *object.Float:
+		a, ok := actual.(*object.Float)
+		if !ok {
+			t.Fatalf(
+				"object type mismatch. got=%T, expected=%T",
+				actual,
+				expected,
+			)
+		}
+		testFloatObject(t, a, e)
 	case *object.Boolean:
 		a, ok := actual.(*object.Boolean)
 		if !ok {
@@ -528,6 +1131,20 @@ func testIntegerObject(
 	}
 }
 
+func testFloatObject(
+	t *testing.T,
+	actual *object.Float,
+	expected *object.Float,
+) {
+	if actual.Value != expected.Value {
+		t.Fatalf(
+			"float value mismatch. got=%v, expected=%v",
+			actual.Value,
+			expected.Value,
+		)
+	}
+}
+
 func testBooleanObject(
 	t *testing.T,
 	actual *object.Boolean,